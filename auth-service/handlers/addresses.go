@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+)
+
+// AddressHandler serves the caller's address book. The first address of a
+// kind becomes the default automatically.
+type AddressHandler struct {
+	addresses repository.AddressRepository
+}
+
+func NewAddressHandler(addresses repository.AddressRepository) *AddressHandler {
+	return &AddressHandler{addresses: addresses}
+}
+
+func addressId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid address ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	addresses, err := h.addresses.ListByUser(ctx, userId)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to query addresses",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"addresses": addresses})
+}
+
+// GetAddress returns one of the caller's addresses. order-service also uses
+// it to resolve the address IDs given when creating an order.
+func (h *AddressHandler) GetAddress(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	id, ok := addressId(c)
+	if !ok {
+		return
+	}
+
+	a, err := h.addresses.Get(ctx, userId, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to query address",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"address": a})
+}
+
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	var a types.Address
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	a.UserId = userId
+
+	if err := h.addresses.Create(ctx, &a); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to create address",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(201, gin.H{"message": "Address created successfully", "address": a})
+}
+
+// UpdateAddress replaces an address's fields. The kind is kept as it was, and
+// the default flag is managed with SetDefaultAddress.
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	id, ok := addressId(c)
+	if !ok {
+		return
+	}
+	var a types.Address
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	a.Id = id
+	a.UserId = userId
+
+	if err := h.addresses.Update(ctx, &a); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to update address",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Address updated successfully", "address": a})
+}
+
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	id, ok := addressId(c)
+	if !ok {
+		return
+	}
+
+	if err := h.addresses.Delete(ctx, userId, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to delete address",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Address deleted successfully"})
+}
+
+func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	id, ok := addressId(c)
+	if !ok {
+		return
+	}
+
+	if err := h.addresses.SetDefault(ctx, userId, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to set default address",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Default address updated"})
+}
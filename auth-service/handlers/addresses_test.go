@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RohithBN/shared/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func addressRequest(h *AddressHandler, method, path, userId, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/me/addresses", h.CreateAddress)
+	router.GET("/me/addresses/:id", h.GetAddress)
+	router.POST("/me/addresses/:id/default", h.SetDefaultAddress)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userId)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const testAddress = `{"kind":"shipping","line1":"1 Main St","city":"Springfield","postal_code":"12345","country":"US"}`
+
+func TestAddressDefaults(t *testing.T) {
+	addresses := repository.NewMemoryAddressRepository()
+	h := NewAddressHandler(addresses)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if w := addressRequest(h, http.MethodPost, "/me/addresses", "7", testAddress); w.Code != 201 {
+			t.Fatalf("create: status = %d, body %s", w.Code, w.Body)
+		}
+	}
+	// The first address of a kind becomes the default
+	list, _ := addresses.ListByUser(ctx, 7)
+	if len(list) != 2 || !list[0].IsDefault || list[0].Id != 1 || list[1].IsDefault {
+		t.Fatalf("addresses = %+v, want only the first as default", list)
+	}
+
+	if w := addressRequest(h, http.MethodPost, "/me/addresses/2/default", "7", ""); w.Code != 200 {
+		t.Fatalf("set default: status = %d, body %s", w.Code, w.Body)
+	}
+	list, _ = addresses.ListByUser(ctx, 7)
+	if list[0].Id != 2 || !list[0].IsDefault || list[1].IsDefault {
+		t.Errorf("addresses = %+v, want only address 2 as default", list)
+	}
+}
+
+func TestAddressesAreScopedToOwner(t *testing.T) {
+	h := NewAddressHandler(repository.NewMemoryAddressRepository())
+	if w := addressRequest(h, http.MethodPost, "/me/addresses", "7", testAddress); w.Code != 201 {
+		t.Fatalf("create: status = %d, body %s", w.Code, w.Body)
+	}
+
+	if w := addressRequest(h, http.MethodGet, "/me/addresses/1", "8", ""); w.Code != 404 {
+		t.Errorf("get as another user: status = %d, want 404", w.Code)
+	}
+	if w := addressRequest(h, http.MethodPost, "/me/addresses/1/default", "8", ""); w.Code != 404 {
+		t.Errorf("set default as another user: status = %d, want 404", w.Code)
+	}
+	if w := addressRequest(h, http.MethodGet, "/me/addresses/1", "7", ""); w.Code != 200 {
+		t.Errorf("get as owner: status = %d, want 200", w.Code)
+	}
+}
@@ -1,41 +1,50 @@
 package handlers
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os"
+	"math"
+	"strconv"
 	"time"
 
-	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/repository"
 	"github.com/RohithBN/shared/types"
-	"github.com/RohithBN/shared/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var Users []types.User
-
-var secretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
-var dbPool *pgxpool.Pool
+// UserHandler serves the account endpoints: registration, login, email
+// verification, password reset and the profile.
+type UserHandler struct {
+	users repository.UserRepository
+	// tx stores new accounts and email changes together with their
+	// verification email event.
+	tx repository.Transactor
+}
 
-func InitDB() error {
-    var err error
-    dbPool, err = utils.GetDB()
-    if err != nil {
-        return fmt.Errorf("failed to initialize database: %v", err)
-    }
-    return nil
+func NewUserHandler(users repository.UserRepository, tx repository.Transactor) *UserHandler {
+	return &UserHandler{users: users, tx: tx}
 }
 
-func Register(c *gin.Context) {
+func (h *UserHandler) Register(c *gin.Context) {
 	var user types.User
 	if err := c.ShouldBind(&user); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	// Emails are unique regardless of case
+	user.Email = normalizeEmail(user.Email)
+	if !events.ValidEmail(user.Email) {
+		c.JSON(400, gin.H{"error": "Invalid email address"})
+		return
+	}
+	if user.Password == "" {
+		c.JSON(400, gin.H{"error": "Password is required"})
+		return
+	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -45,96 +54,154 @@ func Register(c *gin.Context) {
 
 	user.Password = string(hashedPassword)
 	user.CreatedAt = time.Now().Format(time.RFC3339)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to connect to database"})
-		return
-	}
-	_, err = dbPool.Exec(
-		c,
-		`INSERT INTO USERS (name, email, password, created_at) VALUES ($1, $2, $3, $4)`,
-		user.Name,
-		user.Email,
-		user.Password,
-		user.CreatedAt,
-	)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to register user",
-			"details": err.Error()})
-
+	// Never trust roles sent by the client
+	user.Roles = []string{types.RoleCustomer}
+	user.EmailVerified = false
+	// The verification link goes out through the outbox in the same
+	// transaction, so it is sent exactly when the account exists; the
+	// welcome email follows once verified
+	err = h.tx.InTx(c.Request.Context(), func(ctx context.Context) error {
+		if err := h.users.Create(ctx, &user); err != nil {
+			return err
+		}
+		return sendVerificationEmail(ctx, &user)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		c.JSON(409, gin.H{"error": "Email is already registered"})
 		return
 	}
-	Users = append(Users, user)
-
-	//send Message to kafka 
-
-	err=kafka.ProduceEmail(user.Email,user.Name,user.CreatedAt)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to send message to Kafka",
-			"details": err.Error()})
+		// Includes events failing their schema, which is nothing the
+		// client can fix
+		logging.FromContext(c).Error("Failed to register user", "email", user.Email, "error", err)
+		c.JSON(500, gin.H{"error": "Failed to register user"})
 		return
 	}
+	user.Password = ""
 
 	c.JSON(200, gin.H{
-		"message": "User registered successfully",
+		"message": "User registered successfully, check your email to verify your account",
 		"user":    user,
 	})
 }
 
-func Login(c *gin.Context) {
-	var user types.User
-	if err := c.ShouldBind(&user); err != nil {
+func (h *UserHandler) Login(c *gin.Context) {
+	var req types.User
+	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	var err error
-	
-	// Check if the user exists in the database
-	var password string
-	err=dbPool.QueryRow(
-		c,
-		`SELECT id,password FROM USERS WHERE email = $1`,
-		user.Email,
-	).Scan(&user.Id,&password)
+	email := normalizeEmail(req.Email)
+	ip := c.ClientIP()
+
+	// Refuse early while the account is locked, the IP is blocked or the
+	// progressive delay hasn't passed
+	reason, retryAfter, err := loginBlocked(c, email, ip)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			c.JSON(401, gin.H{"error": "User not found"})
-			return
-		}
+		c.JSON(503, gin.H{"error": "Login temporarily unavailable"})
+		return
+	}
+	if reason != "" {
+		h.auditLoginAttempt(c, email, 0, ip, reason)
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(429, gin.H{"error": "Too many failed login attempts, try again later",
+			"retry_after": seconds})
+		return
+	}
+
+	// Check if the user exists in the database
+	user, err := h.users.GetByEmail(c, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		c.JSON(500, gin.H{"error": "Failed to query user",
 			"details": err.Error()})
 		return
 	}
-	// Simulate a successful login
+	found := err == nil
+	if !found {
+		// Unknown emails still pay for a bcrypt comparison so response
+		// times don't reveal which addresses are registered
+		user = &types.User{Email: req.Email, Password: string(dummyHash)}
+	}
 
-	isValidPassword := bcrypt.CompareHashAndPassword([]byte(password), []byte(user.Password))
-	if isValidPassword != nil {
-		c.JSON(401, gin.H{"error": "Invalid password"})
+	isValidPassword := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if !found || isValidPassword != nil {
+		h.loginFailed(c, user, email, ip, found)
 		return
 	}
-	tokenString, err := GenerateJWT(&user)
+	if err := clearLoginFailures(c, email); err != nil {
+		logging.FromContext(c).Error("Failed to clear login failures", "user_id", user.Id, "error", err)
+	}
+	// Only checked after the password so it doesn't reveal which addresses
+	// are registered.
+	if !user.EmailVerified {
+		h.auditLoginAttempt(c, email, user.Id, ip, attemptUnverified)
+		c.JSON(403, gin.H{"error": "Email not verified",
+			"code": "email_not_verified"})
+		return
+	}
+	tokens, err := issueTokens(c, user, "")
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to generate token",
 			"details": err.Error()})
 		return
 	}
-	fmt.Println("Token:", tokenString)
+	h.auditLoginAttempt(c, email, user.Id, ip, attemptSuccess)
+	user.Password = ""
 	c.JSON(200, gin.H{
-		"message": "User logged in successfully",
-		"user":    user,
-		"token":   tokenString,
+		"message":       "User logged in successfully",
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 
 }
-func GenerateJWT(user *types.User) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
+
+// loginFailed records a failed attempt and writes the same response whether
+// the email is unknown or the password is wrong.
+func (h *UserHandler) loginFailed(c *gin.Context, user *types.User, email, ip string, found bool) {
+	userId := 0
+	if found {
+		userId = user.Id
+	}
+	h.auditLoginAttempt(c, email, userId, ip, attemptBadCredential)
+
+	locked, err := recordLoginFailure(c, email, ip)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to record login failure", "error", err)
+	}
+	if locked {
+		logging.FromContext(c).Warn("Account locked after failed logins", "email", email, "ip", ip)
+		if found {
+			sendAccountLockedEmail(c, user)
+		}
+	}
+	c.JSON(401, gin.H{"error": "Invalid email or password"})
+}
+
+// GenerateJWT signs a short-lived access token for user and returns it along
+// with its jti, which is what gets revoked on logout. sid is the login
+// session (refresh token family) the token belongs to.
+func GenerateJWT(user *types.User, sid string) (string, string, error) {
+	now := time.Now()
+	jti := randomToken(16)
+
+	claims := jwt.MapClaims{}
 	claims["id"] = user.Id
 	claims["email"] = user.Email
 	claims["name"] = user.Name
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
+	claims["roles"] = user.Roles
+	claims["jti"] = jti
+	claims["sid"] = sid
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(accessTokenTTL()).Unix()
 
-	// Make sure we're using the same secret key as in .env
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+	signed, err := signToken(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return signed, jti, nil
 }
 
 
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setupRedis points the handlers at an in-process Redis for the test.
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redis.RedisClient = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redis.RedisClient.Close() })
+	return mr
+}
+
+func newTestUserHandler(t *testing.T) (*UserHandler, *repository.MemoryUsers) {
+	t.Helper()
+	setupRedis(t)
+	t.Setenv("JWT_SIGNING_ALG", "")
+	t.Setenv("JWT_KEYS_DIR", "")
+	if err := InitKeys(); err != nil {
+		t.Fatal(err)
+	}
+	users := repository.NewMemoryUserRepository()
+	return NewUserHandler(users, repository.MemoryTransactor{}), users
+}
+
+func createTestUser(t *testing.T, users *repository.MemoryUsers, email, password string, verified bool) *types.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &types.User{Name: "Test User", Email: email, Password: string(hash), Roles: []string{types.RoleCustomer}, EmailVerified: verified}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func login(h *UserHandler, email, password string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", h.Login)
+
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLogin(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	user := createTestUser(t, users, "alice@example.com", "correct horse", true)
+
+	// Emails are matched regardless of case and surrounding spaces
+	w := login(h, " Alice@Example.com ", "correct horse")
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var resp struct {
+		User         types.User `json:"user"`
+		Token        string     `json:"token"`
+		RefreshToken string     `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Errorf("missing tokens in %s", w.Body)
+	}
+	if resp.User.Id != user.Id || resp.User.Password != "" {
+		t.Errorf("user = %+v, want id %d without the password hash", resp.User, user.Id)
+	}
+	if n := len(users.Attempts); n != 1 || users.Attempts[0].Reason != attemptSuccess {
+		t.Errorf("login attempts = %+v, want one success", users.Attempts)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	createTestUser(t, users, "alice@example.com", "correct horse", true)
+
+	wrong := login(h, "alice@example.com", "battery staple")
+	if wrong.Code != 401 {
+		t.Fatalf("wrong password: status = %d, want 401", wrong.Code)
+	}
+	unknown := login(h, "bob@example.com", "battery staple")
+	if unknown.Code != 401 {
+		t.Fatalf("unknown email: status = %d, want 401", unknown.Code)
+	}
+	// The response must not reveal which addresses are registered
+	if wrong.Body.String() != unknown.Body.String() {
+		t.Errorf("responses differ: %s vs %s", wrong.Body, unknown.Body)
+	}
+	for _, a := range users.Attempts {
+		if a.Success || a.Reason != attemptBadCredential {
+			t.Errorf("attempt = %+v, want a failed %s", a, attemptBadCredential)
+		}
+	}
+}
+
+func TestLoginDelayAfterRepeatedFailures(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	createTestUser(t, users, "alice@example.com", "correct horse", true)
+
+	login(h, "alice@example.com", "wrong")
+	login(h, "alice@example.com", "wrong")
+
+	// The second failure starts a delay, which holds even for the right password
+	w := login(h, "alice@example.com", "correct horse")
+	if w.Code != 429 {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+}
+
+func TestLoginUnverifiedEmail(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	createTestUser(t, users, "alice@example.com", "correct horse", false)
+
+	w := login(h, "alice@example.com", "correct horse")
+	if w.Code != 403 {
+		t.Fatalf("status = %d, want 403", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "email_not_verified") {
+		t.Errorf("body = %s, want the email_not_verified code", w.Body)
+	}
+}
+
+func register(h *UserHandler, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/register", h.Register)
+
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegister(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-secret")
+	emails := repository.NewMemoryOutbox()
+	kafka.InitOutbox(emails)
+
+	w := register(h, `{"name":"Alice","email":" Alice@Example.com ","password":"correct horse","roles":["admin"]}`)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	user, err := users.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@example.com" || user.EmailVerified || len(user.Roles) != 1 || user.Roles[0] != types.RoleCustomer {
+		t.Errorf("stored user = %+v", user)
+	}
+	if n := len(emails.Pending()); n != 1 {
+		t.Errorf("got %d verification emails, want 1", n)
+	}
+}
+
+func TestRegisterRejectsInvalidInput(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-secret")
+	kafka.InitOutbox(repository.NewMemoryOutbox())
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing email", `{"name":"Alice","password":"correct horse"}`},
+		{"malformed email", `{"name":"Alice","email":"alice@","password":"correct horse"}`},
+		{"display name", `{"name":"Alice","email":"Alice <alice@example.com>","password":"correct horse"}`},
+		{"missing password", `{"name":"Alice","email":"alice@example.com"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := register(h, tt.body)
+			if w.Code != 400 {
+				t.Fatalf("status = %d, want 400 (body %s)", w.Code, w.Body)
+			}
+		})
+	}
+	if _, err := users.GetByEmail(context.Background(), "alice@example.com"); err == nil {
+		t.Error("user created from invalid input")
+	}
+}
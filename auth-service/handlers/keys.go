@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one private key the auth service can sign with.
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+// keySet holds every active key. All of them are published in the JWKS so
+// tokens signed by a key that is being rotated out keep verifying; new tokens
+// are signed with current.
+type keySet struct {
+	alg     string
+	keys    []*signingKey
+	current *signingKey
+}
+
+var keys *keySet
+
+// InitKeys loads signing keys according to JWT_SIGNING_ALG:
+//
+//	RS256 (default)  PEM private keys from JWT_KEYS_DIR; the file name
+//	/ ES256          without extension is the kid. JWT_SIGNING_KID picks
+//	                 the key to sign with, otherwise the last kid in sort
+//	                 order is used so rotating means adding a newer file.
+//	HS256            shared JWT_SECRET_KEY, an explicit opt-in for local
+//	                 development only
+//
+// Without JWT_KEYS_DIR an ephemeral key is generated, which is only useful
+// for a single auth-service instance.
+func InitKeys() error {
+	alg := strings.ToUpper(os.Getenv("JWT_SIGNING_ALG"))
+	if alg == "" {
+		alg = "RS256"
+	}
+
+	set := &keySet{alg: alg}
+	switch alg {
+	case "HS256":
+		if os.Getenv("JWT_SECRET_KEY") == "" {
+			return fmt.Errorf("JWT_SECRET_KEY is required for HS256")
+		}
+		log.Printf("Signing tokens with the shared HS256 secret, which is for local development only")
+		keys = set
+		return nil
+	case "RS256", "ES256":
+	default:
+		return fmt.Errorf("unsupported JWT_SIGNING_ALG %q", alg)
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Printf("JWT_KEYS_DIR not set, generating an ephemeral %s key", alg)
+		k, err := generateKey(alg)
+		if err != nil {
+			return err
+		}
+		set.keys = []*signingKey{k}
+	} else {
+		loaded, err := loadKeys(dir, alg)
+		if err != nil {
+			return err
+		}
+		set.keys = loaded
+	}
+	if len(set.keys) == 0 {
+		return fmt.Errorf("no %s keys found in %s", alg, dir)
+	}
+
+	set.current = set.keys[len(set.keys)-1]
+	if kid := os.Getenv("JWT_SIGNING_KID"); kid != "" {
+		set.current = nil
+		for _, k := range set.keys {
+			if k.kid == kid {
+				set.current = k
+			}
+		}
+		if set.current == nil {
+			return fmt.Errorf("JWT_SIGNING_KID %q not found", kid)
+		}
+	}
+
+	keys = set
+	log.Printf("Signing tokens with %s key %s (%d active)", alg, set.current.kid, len(set.keys))
+	return nil
+}
+
+func loadKeys(dir, alg string) ([]*signingKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var loaded []*signingKey
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %v", file, err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data in %s", file)
+		}
+		parsed, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s: %v", file, err)
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			if alg != "RS256" {
+				return nil, fmt.Errorf("key %s is RSA but JWT_SIGNING_ALG is %s", file, alg)
+			}
+			loaded = append(loaded, &signingKey{kid: kid, method: jwt.SigningMethodRS256, key: k})
+		case *ecdsa.PrivateKey:
+			if alg != "ES256" || k.Curve != elliptic.P256() {
+				return nil, fmt.Errorf("key %s must be a P-256 key for %s", file, alg)
+			}
+			loaded = append(loaded, &signingKey{kid: kid, method: jwt.SigningMethodES256, key: k})
+		default:
+			return nil, fmt.Errorf("unsupported key type in %s", file)
+		}
+	}
+	return loaded, nil
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
+
+func generateKey(alg string) (*signingKey, error) {
+	kid := "ephemeral-" + randomToken(6)
+	if alg == "ES256" {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodES256, key: k}, nil
+	}
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: kid, method: jwt.SigningMethodRS256, key: k}, nil
+}
+
+// signToken signs claims with the current key, stamping its kid.
+func signToken(claims jwt.MapClaims) (string, error) {
+	if keys == nil {
+		return "", fmt.Errorf("signing keys are not initialized")
+	}
+	if keys.alg == "HS256" {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		// Make sure we're using the same secret key as in .env
+		return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+	}
+	token := jwt.NewWithClaims(keys.current.method, claims)
+	token.Header["kid"] = keys.current.kid
+	return token.SignedString(keys.current.key)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS publishes the public halves of all active signing keys.
+func JWKS(c *gin.Context) {
+	set := []jwk{}
+	if keys != nil {
+		for _, k := range keys.keys {
+			set = append(set, toJWK(k))
+		}
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, gin.H{"keys": set})
+}
+
+func toJWK(k *signingKey) jwk {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.key.Public().(type) {
+	case *rsa.PublicKey:
+		return jwk{
+			Kty: "RSA", Kid: k.kid, Use: "sig", Alg: k.method.Alg(),
+			N: b64(pub.N.Bytes()),
+			E: b64(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return jwk{
+			Kty: "EC", Kid: k.kid, Use: "sig", Alg: k.method.Alg(), Crv: "P-256",
+			X: b64(pub.X.FillBytes(make([]byte, size))),
+			Y: b64(pub.Y.FillBytes(make([]byte, size))),
+		}
+	}
+	return jwk{Kid: k.kid}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/types"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Failed logins are counted in Redis per submitted email (whether or not the
+// account exists, so lockouts don't reveal registered addresses) and per
+// client IP:
+//
+//	login_failures:<email>    failures within the window
+//	login_delay:<email>       set while the next attempt must wait
+//	login_locked:<email>      set while the account is locked out
+//	login_ip_failures:<ip>    failures from the IP within the window
+//
+// Each failure after the first doubles the wait before the next attempt; at
+// the threshold the account is locked.
+func loginFailuresKey(email string) string { return "login_failures:" + email }
+func loginDelayKey(email string) string    { return "login_delay:" + email }
+func loginLockedKey(email string) string   { return "login_locked:" + email }
+func loginIPFailuresKey(ip string) string  { return "login_ip_failures:" + ip }
+
+const maxLoginDelay = 30 * time.Second
+
+// Thresholds are read lazily because .env is loaded in main.
+func loginLockoutThreshold() int64 { return intFromEnv("LOGIN_LOCKOUT_THRESHOLD", 5) }
+func loginIPThreshold() int64      { return intFromEnv("LOGIN_IP_THRESHOLD", 20) }
+func loginLockoutDuration() time.Duration {
+	return durationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+func loginFailureWindow() time.Duration {
+	return durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+}
+
+// Reasons recorded in login_attempts
+const (
+	attemptSuccess       = "success"
+	attemptBadCredential = "invalid_credentials"
+	attemptUnverified    = "email_not_verified"
+	attemptLocked        = "locked"
+	attemptDelayed       = "delayed"
+	attemptIPBlocked     = "ip_blocked"
+)
+
+// dummyHash is compared against when the email is unknown so both failure
+// paths spend the same time in bcrypt.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginBlocked reports whether email or ip must wait before trying again, and
+// for how long.
+func loginBlocked(ctx context.Context, email, ip string) (string, time.Duration, error) {
+	pipe := redis.RedisClient.Pipeline()
+	locked := pipe.PTTL(ctx, loginLockedKey(email))
+	delay := pipe.PTTL(ctx, loginDelayKey(email))
+	ipFailures := pipe.Get(ctx, loginIPFailuresKey(ip))
+	ipTTL := pipe.PTTL(ctx, loginIPFailuresKey(ip))
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return "", 0, err
+	}
+
+	if ttl := locked.Val(); ttl > 0 {
+		return attemptLocked, ttl, nil
+	}
+	if n, _ := ipFailures.Int64(); n >= loginIPThreshold() {
+		return attemptIPBlocked, ipTTL.Val(), nil
+	}
+	if ttl := delay.Val(); ttl > 0 {
+		return attemptDelayed, ttl, nil
+	}
+	return "", 0, nil
+}
+
+// recordLoginFailure counts a failed attempt and returns true if it locked
+// the account.
+func recordLoginFailure(ctx context.Context, email, ip string) (bool, error) {
+	window := loginFailureWindow()
+
+	pipe := redis.RedisClient.TxPipeline()
+	failures := pipe.Incr(ctx, loginFailuresKey(email))
+	pipe.ExpireNX(ctx, loginFailuresKey(email), window)
+	pipe.Incr(ctx, loginIPFailuresKey(ip))
+	pipe.ExpireNX(ctx, loginIPFailuresKey(ip), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	n := failures.Val()
+	if n >= loginLockoutThreshold() {
+		pipe := redis.RedisClient.TxPipeline()
+		pipe.Set(ctx, loginLockedKey(email), 1, loginLockoutDuration())
+		pipe.Del(ctx, loginFailuresKey(email), loginDelayKey(email))
+		_, err := pipe.Exec(ctx)
+		return err == nil, err
+	}
+	if n > 1 {
+		delay := maxLoginDelay
+		if n-2 < 5 {
+			delay = time.Second << (n - 2)
+		}
+		return false, redis.RedisClient.Set(ctx, loginDelayKey(email), 1, delay).Err()
+	}
+	return false, nil
+}
+
+// clearLoginFailures resets the account's counters after a successful login.
+// The IP counter is left alone so one valid account can't be used to reset it.
+func clearLoginFailures(ctx context.Context, email string) error {
+	return redis.RedisClient.Del(ctx, loginFailuresKey(email), loginDelayKey(email)).Err()
+}
+
+// auditLoginAttempt writes to login_attempts. Failures are logged rather than
+// failing the login.
+func (h *UserHandler) auditLoginAttempt(ctx context.Context, email string, userId int, ip, result string) {
+	err := h.users.RecordLoginAttempt(ctx, types.LoginAttempt{
+		Email:       email,
+		UserId:      userId,
+		IP:          ip,
+		Success:     result == attemptSuccess,
+		Reason:      result,
+		AttemptedAt: time.Now(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to record login attempt", "email", email, "error", err)
+	}
+}
+
+func sendAccountLockedEmail(ctx context.Context, user *types.User) {
+	err := kafka.ProduceEmailEvent(ctx, events.EmailRequested{
+		Kind:        events.EmailAccountLocked,
+		Email:       user.Email,
+		Name:        user.Name,
+		LockedUntil: time.Now().Add(loginLockoutDuration()).Format(time.RFC3339),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to send account locked email", "user_id", user.Id, "error", err)
+	}
+}
+
+func intFromEnv(name string, def int64) int64 {
+	if n, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
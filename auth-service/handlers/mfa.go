@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// TOTP enrolment lives in user_mfa (secret encrypted, enabled once the first
+// code is confirmed) and mfa_recovery_codes (SHA-256 hashes). Every change
+// and every recovery code use is written to mfa_audit.
+const recoveryCodeCount = 10
+
+// Events recorded in mfa_audit
+const (
+	mfaEnrollStarted    = "totp_enroll_started"
+	mfaEnabled          = "totp_enabled"
+	mfaDisabled         = "totp_disabled"
+	mfaStepUp           = "totp_step_up"
+	mfaRecoveryCodeUsed = "recovery_code_used"
+	mfaFailed           = "totp_failed"
+)
+
+// Wrong codes are counted per user; after maxMFAFailures within the window
+// further attempts are refused until it expires.
+const (
+	maxMFAFailures = 5
+	mfaFailureTTL  = 15 * time.Minute
+)
+
+var (
+	errMFANotEnabled  = errors.New("TOTP is not enabled")
+	errInvalidMFACode = errors.New("invalid code")
+	errMFALocked      = errors.New("too many invalid codes")
+)
+
+// MFAHandler serves TOTP enrolment and the TOTP step-up check.
+type MFAHandler struct {
+	mfa repository.MFARepository
+}
+
+func NewMFAHandler(mfa repository.MFARepository) *MFAHandler {
+	return &MFAHandler{mfa: mfa}
+}
+
+func mfaFailuresKey(userId int) string { return fmt.Sprintf("mfa_failures:%d", userId) }
+
+// totpUsedKey marks a time step as used so a code can't be replayed within
+// its validity window.
+func totpUsedKey(userId int, step int64) string { return fmt.Sprintf("totp_used:%d:%d", userId, step) }
+
+// mfaCaller reads the user the gateway authenticated.
+func mfaCaller(c *gin.Context) (int, string, bool) {
+	userId, err := strconv.Atoi(c.GetHeader("X-User-ID"))
+	if err != nil {
+		c.JSON(401, gin.H{"error": "User ID not found"})
+		return 0, "", false
+	}
+	return userId, c.GetHeader("X-User-Email"), true
+}
+
+func (h *MFAHandler) audit(ctx context.Context, userId int, event, ip string) {
+	if err := h.mfa.RecordAudit(ctx, userId, event, ip); err != nil {
+		logging.FromContext(ctx).Error("Failed to record MFA event", "user_id", userId, "event", event, "error", err)
+	}
+}
+
+// loadTOTPSecret returns the user's decrypted secret and whether enrolment
+// has been confirmed.
+func (h *MFAHandler) loadTOTPSecret(ctx context.Context, userId int) (string, bool, error) {
+	encrypted, enabled, err := h.mfa.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", false, errMFANotEnabled
+		}
+		return "", false, err
+	}
+	secret, err := decryptSecret(encrypted)
+	if err != nil {
+		return "", false, err
+	}
+	return secret, enabled, nil
+}
+
+// checkTOTP validates code and consumes its time step.
+func checkTOTP(ctx context.Context, userId int, secret, code string) (bool, error) {
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	fresh, err := redis.RedisClient.SetNX(ctx, totpUsedKey(userId, step), 1, (2*totpSkew+1)*totpPeriod*time.Second).Result()
+	if err != nil {
+		return false, err
+	}
+	return fresh, nil
+}
+
+// verifySecondFactor accepts a TOTP code or a recovery code for an enabled
+// enrolment and returns which one was used.
+func (h *MFAHandler) verifySecondFactor(ctx context.Context, userId int, code, ip string) (string, error) {
+	failures, err := redis.RedisClient.Get(ctx, mfaFailuresKey(userId)).Int()
+	if err == nil && failures >= maxMFAFailures {
+		return "", errMFALocked
+	}
+
+	secret, enabled, err := h.loadTOTPSecret(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "", errMFANotEnabled
+	}
+
+	var ok bool
+	method := mfaStepUp
+	if len(code) == totpDigits {
+		ok, err = checkTOTP(ctx, userId, secret, code)
+	} else {
+		method = mfaRecoveryCodeUsed
+		ok, err = h.mfa.UseRecoveryCode(ctx, userId, hashToken(normalizeRecoveryCode(code)))
+	}
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		pipe := redis.RedisClient.TxPipeline()
+		pipe.Incr(ctx, mfaFailuresKey(userId))
+		pipe.ExpireNX(ctx, mfaFailuresKey(userId), mfaFailureTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logging.FromContext(ctx).Error("Failed to count MFA failure", "user_id", userId, "error", err)
+		}
+		h.audit(ctx, userId, mfaFailed, ip)
+		return "", errInvalidMFACode
+	}
+
+	redis.RedisClient.Del(ctx, mfaFailuresKey(userId))
+	if method == mfaRecoveryCodeUsed {
+		h.audit(ctx, userId, mfaRecoveryCodeUsed, ip)
+	}
+	return method, nil
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errMFANotEnabled):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, errInvalidMFACode):
+		c.JSON(401, gin.H{"error": "Invalid code"})
+	case errors.Is(err, errMFALocked):
+		c.JSON(429, gin.H{"error": "Too many invalid codes, try again later"})
+	default:
+		c.JSON(500, gin.H{"error": "Failed to verify code",
+			"details": err.Error()})
+	}
+}
+
+// EnrollTOTP starts enrolment by generating a secret. It only takes effect
+// once ConfirmTOTP succeeds, so a half-finished enrolment can simply be
+// restarted.
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, email, ok := mfaCaller(c)
+	if !ok {
+		return
+	}
+
+	_, enabled, err := h.loadTOTPSecret(ctx, userId)
+	if err != nil && !errors.Is(err, errMFANotEnabled) {
+		c.JSON(500, gin.H{"error": "Failed to load MFA settings",
+			"details": err.Error()})
+		return
+	}
+	if enabled {
+		c.JSON(409, gin.H{"error": "TOTP is already enabled"})
+		return
+	}
+
+	secret := generateTOTPSecret()
+	encrypted, err := encryptSecret(secret)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to store TOTP secret",
+			"details": err.Error()})
+		return
+	}
+	if err := h.mfa.StartTOTP(ctx, userId, encrypted); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to store TOTP secret",
+			"details": err.Error()})
+		return
+	}
+	h.audit(ctx, userId, mfaEnrollStarted, c.ClientIP())
+
+	c.JSON(200, gin.H{
+		"message":     "Scan the QR code with your authenticator app, then confirm with a code",
+		"secret":      secret,
+		"otpauth_uri": totpProvisioningURI(secret, email),
+	})
+}
+
+// ConfirmTOTP enables TOTP after checking the first code and returns the
+// recovery codes. They are only ever shown here.
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, _, ok := mfaCaller(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, enabled, err := h.loadTOTPSecret(ctx, userId)
+	if err != nil {
+		if errors.Is(err, errMFANotEnabled) {
+			c.JSON(400, gin.H{"error": "Start enrolment first"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to load MFA settings",
+			"details": err.Error()})
+		return
+	}
+	if enabled {
+		c.JSON(409, gin.H{"error": "TOTP is already enabled"})
+		return
+	}
+	valid, err := checkTOTP(ctx, userId, secret, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	if !valid {
+		writeMFAError(c, errInvalidMFACode)
+		return
+	}
+
+	codes := generateRecoveryCodes(recoveryCodeCount)
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashToken(code)
+	}
+	if err := h.mfa.EnableTOTP(ctx, userId, hashes); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to enable TOTP",
+			"details": err.Error()})
+		return
+	}
+	h.audit(ctx, userId, mfaEnabled, c.ClientIP())
+
+	c.JSON(200, gin.H{
+		"message":        "TOTP enabled. Store the recovery codes somewhere safe; each works once",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP removes the enrolment after checking a TOTP or recovery code.
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, _, ok := mfaCaller(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.verifySecondFactor(ctx, userId, req.Code, c.ClientIP()); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(ctx, userId); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to disable TOTP",
+			"details": err.Error()})
+		return
+	}
+	h.audit(ctx, userId, mfaDisabled, c.ClientIP())
+
+	c.JSON(200, gin.H{"message": "TOTP disabled"})
+}
+
+// VerifyTOTP is the step-up check. On success order-service's
+// TwoFacAuthMiddleware lets the caller's session place orders, exactly as
+// after the emailed OTP.
+func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, _, ok := mfaCaller(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessionId := c.GetHeader("X-Session-ID")
+	if sessionId == "" {
+		c.JSON(401, gin.H{"error": "Session not found"})
+		return
+	}
+
+	method, err := h.verifySecondFactor(ctx, userId, req.Code, c.ClientIP())
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	if err := redis.MarkSteppedUp(ctx, userId, sessionId, redis.StepUpTOTP); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to set user verified flag in redis"})
+		return
+	}
+	if method == mfaStepUp {
+		h.audit(ctx, userId, mfaStepUp, c.ClientIP())
+	}
+
+	c.JSON(200, gin.H{"message": "Code verified successfully"})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reset tokens are random, single-use and short-lived. Only their hash is
+// stored:
+//
+//	password_reset:<hash>         user id, expires with the token
+//	password_reset_user:<user_id> hash of the user's current token
+//
+// Requesting a new token invalidates the previous one.
+const passwordResetTTL = 30 * time.Minute
+
+func passwordResetKey(hash string) string    { return "password_reset:" + hash }
+func passwordResetUserKey(userId int) string { return "password_reset_user:" + strconv.Itoa(userId) }
+
+// passwordResetLink points at the page where the user picks a new password,
+// which then calls ResetPassword with the token. PASSWORD_RESET_URL overrides
+// the default of APP_BASE_URL + /reset-password.
+func passwordResetLink(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		base = appBaseURL() + "/reset-password"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+func sendPasswordResetEmail(ctx context.Context, user *types.User) error {
+	token := randomToken(32)
+	hash := hashToken(token)
+
+	previous, err := redis.RedisClient.Get(ctx, passwordResetUserKey(user.Id)).Result()
+	if err != nil && err != goredis.Nil {
+		return err
+	}
+
+	pipe := redis.RedisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, passwordResetKey(previous))
+	}
+	pipe.Set(ctx, passwordResetKey(hash), user.Id, passwordResetTTL)
+	pipe.Set(ctx, passwordResetUserKey(user.Id), hash, passwordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return kafka.ProduceEmailEvent(ctx, events.EmailRequested{
+		Kind:  events.EmailPasswordReset,
+		Email: user.Email,
+		Name:  user.Name,
+		Link:  passwordResetLink(token),
+	})
+}
+
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Same per-address budget as verification resends
+	allowed, remaining, err := redis.TakeToken(ctx, "password_forgot:"+email, resendBurst, resendRefillPerSec, 1)
+	if err != nil {
+		c.JSON(503, gin.H{"error": "Rate limiter unavailable"})
+		return
+	}
+	if !allowed {
+		retryAfter := redis.RetryAfter(remaining, resendRefillPerSec)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.JSON(429, gin.H{"error": "Too many password reset emails requested",
+			"retry_after": int(retryAfter.Seconds())})
+		return
+	}
+
+	// Every outcome below gives the same response so this endpoint can't be
+	// used to discover accounts.
+	const response = "If the account exists, a password reset email has been sent"
+
+	user, err := h.users.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			logging.FromContext(ctx).Error("Failed to query user", "error", err)
+		}
+		c.JSON(200, gin.H{"message": response})
+		return
+	}
+
+	if err := sendPasswordResetEmail(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("Failed to send password reset email", "user_id", user.Id, "error", err)
+	}
+	c.JSON(200, gin.H{"message": response})
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// GETDEL makes the token single-use even with concurrent requests
+	hash := hashToken(req.Token)
+	value, err := redis.RedisClient.GetDel(ctx, passwordResetKey(hash)).Result()
+	if err != nil {
+		if err == goredis.Nil {
+			c.JSON(400, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to reset password",
+			"details": err.Error()})
+		return
+	}
+	userId, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	redis.RedisClient.Del(ctx, passwordResetUserKey(userId))
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = h.users.SetPassword(ctx, userId, string(hashedPassword))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(400, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to reset password",
+			"details": err.Error()})
+		return
+	}
+	// Following the emailed link also proves ownership of the address
+	if err := h.users.SetEmailVerified(ctx, userId, true); err != nil {
+		logging.FromContext(ctx).Error("Failed to mark email verified after password reset", "user_id", userId, "error", err)
+	}
+
+	// Whoever knew the old password may still hold a session
+	if err := revokeAllSessions(ctx, userId); err != nil {
+		logging.FromContext(ctx).Error("Failed to revoke sessions after password reset", "user_id", userId, "error", err)
+		c.JSON(500, gin.H{"error": "Password was reset but existing sessions could not be revoked",
+			"details": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Password reset successfully"})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// callerId reads the user the gateway authenticated.
+func callerId(c *gin.Context) (int, bool) {
+	userId, err := strconv.Atoi(c.GetHeader("X-User-ID"))
+	if err != nil {
+		c.JSON(401, gin.H{"error": "User ID not found"})
+		return 0, false
+	}
+	return userId, true
+}
+
+// loadUser returns the caller's account with the password hash moved out
+// of the struct, so it can't end up in a response.
+func (h *UserHandler) loadUser(ctx context.Context, userId int) (*types.User, string, error) {
+	user, err := h.users.GetByID(ctx, userId)
+	if err != nil {
+		return nil, "", err
+	}
+	password := user.Password
+	user.Password = ""
+	return user, password, nil
+}
+
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	user, _, err := h.loadUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to query user",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"user": user})
+}
+
+// UpdateProfile changes the name and/or email. Changing the email needs the
+// current password and makes the account unverified until the new address
+// is confirmed.
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name            *string `json:"name"`
+		Email           *string `json:"email"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, password, err := h.loadUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to query user",
+			"details": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(400, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		user.Name = name
+	}
+	emailChanged := false
+	if req.Email != nil && normalizeEmail(*req.Email) != normalizeEmail(user.Email) {
+		email := normalizeEmail(*req.Email)
+		// The same check the verification event's schema makes
+		if !events.ValidEmail(email) {
+			c.JSON(400, gin.H{"error": "Invalid email"})
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(password), []byte(req.CurrentPassword)) != nil {
+			c.JSON(401, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+		user.Email = email
+		user.EmailVerified = false
+		emailChanged = true
+	}
+
+	// The new address is only saved together with its verification link
+	err = h.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := h.users.UpdateProfile(ctx, user); err != nil {
+			return err
+		}
+		if emailChanged {
+			return sendVerificationEmail(ctx, user)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(409, gin.H{"error": "Email is already in use"})
+			return
+		}
+		logging.FromContext(ctx).Error("Failed to update profile", "user_id", user.Id, "error", err)
+		c.JSON(500, gin.H{"error": "Failed to update profile"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Profile updated successfully", "user": user})
+}
+
+// ChangePassword sets a new password after checking the current one and
+// signs out every other session.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, ok := callerId(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=8"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, password, err := h.loadUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to query user",
+			"details": err.Error()})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(password), []byte(req.CurrentPassword)) != nil {
+		c.JSON(401, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	if err := h.users.SetPassword(ctx, userId, string(hashedPassword)); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to change password",
+			"details": err.Error()})
+		return
+	}
+
+	if err := revokeOtherSessions(ctx, userId, c.GetHeader("X-Session-ID")); err != nil {
+		c.JSON(500, gin.H{"error": "Password was changed but other sessions could not be revoked",
+			"details": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Password changed successfully"})
+}
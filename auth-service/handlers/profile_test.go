@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func updateProfile(h *UserHandler, userId int, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/me", h.UpdateProfile)
+
+	req := httptest.NewRequest(http.MethodPatch, "/me", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", strconv.Itoa(userId))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateProfileEmail(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-secret")
+	emails := repository.NewMemoryOutbox()
+	kafka.InitOutbox(emails)
+	user := createTestUser(t, users, "alice@example.com", "correct horse", true)
+
+	w := updateProfile(h, user.Id, `{"email":"Alice@New.example.com","current_password":"correct horse"}`)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	saved, _ := users.GetByID(context.Background(), user.Id)
+	if saved.Email != "alice@new.example.com" || saved.EmailVerified {
+		t.Errorf("stored user = %+v, want the new address unverified", saved)
+	}
+	if n := len(emails.Pending()); n != 1 {
+		t.Errorf("got %d verification emails, want 1", n)
+	}
+}
+
+func TestUpdateProfileRejectsInvalidEmail(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-secret")
+	emails := repository.NewMemoryOutbox()
+	kafka.InitOutbox(emails)
+	user := createTestUser(t, users, "alice@example.com", "correct horse", true)
+
+	for _, email := range []string{"a@", "no-at-sign", "Alice <alice@new.example.com>"} {
+		w := updateProfile(h, user.Id, `{"email":"`+email+`","current_password":"correct horse"}`)
+		if w.Code != 400 {
+			t.Errorf("%q: status = %d, want 400", email, w.Code)
+		}
+	}
+	saved, _ := users.GetByID(context.Background(), user.Id)
+	if saved.Email != "alice@example.com" || !saved.EmailVerified {
+		t.Errorf("stored user changed: %+v", saved)
+	}
+	if n := len(emails.Pending()); n != 0 {
+		t.Errorf("got %d verification emails, want none", n)
+	}
+}
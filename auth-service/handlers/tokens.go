@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Token lifetimes are read lazily because .env is loaded in main.
+func accessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+func refreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour)
+}
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// Refresh tokens are opaque random strings. Only their SHA-256 hash is kept
+// in Redis:
+//
+//	refresh:<hash>          hash {user_id, family, jti, uses}
+//	refresh_family:<family> set of refresh token hashes issued in the family
+//	user_families:<user_id> set of the user's live families
+//
+// Every refresh rotates the token within its family and reloads the user, so
+// role changes apply from the next refresh. Presenting a token that was
+// already used means it leaked, so the whole family is revoked.
+func refreshKey(hash string) string         { return "refresh:" + hash }
+func refreshFamilyKey(family string) string { return "refresh_family:" + family }
+func userFamiliesKey(userId int) string     { return fmt.Sprintf("user_families:%d", userId) }
+
+type tokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// issueTokens signs a new access token and stores a new refresh token in
+// family. An empty family starts a new one (i.e. a new login session).
+func issueTokens(ctx context.Context, user *types.User, family string) (*tokenPair, error) {
+	if family == "" {
+		family = randomToken(16)
+	}
+
+	accessToken, jti, err := GenerateJWT(user, family)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := randomToken(32)
+	hash := hashToken(refreshToken)
+
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.HSet(ctx, refreshKey(hash), map[string]interface{}{
+		"user_id": user.Id,
+		"family":  family,
+		"jti":     jti,
+		"uses":    0,
+	})
+	pipe.Expire(ctx, refreshKey(hash), refreshTokenTTL())
+	pipe.SAdd(ctx, refreshFamilyKey(family), hash)
+	pipe.Expire(ctx, refreshFamilyKey(family), refreshTokenTTL())
+	pipe.SAdd(ctx, userFamiliesKey(user.Id), family)
+	pipe.Expire(ctx, userFamiliesKey(user.Id), refreshTokenTTL())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %v", err)
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// rotateRefreshToken consumes refreshToken and issues a new pair in the same
+// family for the user as currently stored.
+func (h *UserHandler) rotateRefreshToken(ctx context.Context, refreshToken string) (*tokenPair, error) {
+	key := refreshKey(hashToken(refreshToken))
+
+	// HINCRBY is atomic, so exactly one caller ever sees uses == 1
+	uses, err := redis.RedisClient.HIncrBy(ctx, key, "uses", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	record, err := redis.RedisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if record["family"] == "" {
+		// HINCRBY created an empty hash for an unknown token
+		redis.RedisClient.Del(ctx, key)
+		return nil, errInvalidRefreshToken
+	}
+	userId, _ := strconv.Atoi(record["user_id"])
+	if uses > 1 {
+		if err := revokeFamily(ctx, userId, record["family"]); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshTokenReused
+	}
+
+	user, err := h.users.GetByID(ctx, userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		if err := revokeFamily(ctx, userId, record["family"]); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issueTokens(ctx, user, record["family"])
+}
+
+// revokeFamily deletes every refresh token in userId's family and revokes
+// the access tokens that were issued with them.
+func revokeFamily(ctx context.Context, userId int, family string) error {
+	hashes, err := redis.RedisClient.SMembers(ctx, refreshFamilyKey(family)).Result()
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		jti, err := redis.RedisClient.HGet(ctx, refreshKey(hash), "jti").Result()
+		if err != nil && err != goredis.Nil {
+			return err
+		}
+		if err := redis.RevokeToken(ctx, jti, accessTokenTTL()); err != nil {
+			return err
+		}
+		if err := redis.RedisClient.Del(ctx, refreshKey(hash)).Err(); err != nil {
+			return err
+		}
+	}
+	if err := redis.RedisClient.Del(ctx, refreshFamilyKey(family)).Err(); err != nil {
+		return err
+	}
+	return redis.RedisClient.SRem(ctx, userFamiliesKey(userId), family).Err()
+}
+
+// revokeAllSessions revokes every refresh token family of userId.
+func revokeAllSessions(ctx context.Context, userId int) error {
+	return revokeOtherSessions(ctx, userId, "")
+}
+
+// revokeOtherSessions revokes every refresh token family of userId except
+// keep, the caller's own session.
+func revokeOtherSessions(ctx context.Context, userId int, keep string) error {
+	families, err := redis.RedisClient.SMembers(ctx, userFamiliesKey(userId)).Result()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if family == keep {
+			continue
+		}
+		if err := revokeFamily(ctx, userId, family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(400, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, err := h.rotateRefreshToken(c, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+			c.JSON(401, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to refresh token",
+			"details": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Token refreshed successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout revokes the caller's access token (X-Token-ID, set by the gateway)
+// and, if given, the session the refresh token belongs to.
+func Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.ShouldBindJSON(&req)
+
+	if err := redis.RevokeToken(c, c.GetHeader("X-Token-ID"), accessTokenTTL()); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	if req.RefreshToken != "" {
+		record, err := redis.RedisClient.HGetAll(c, refreshKey(hashToken(req.RefreshToken))).Result()
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		if family := record["family"]; family != "" {
+			userId, _ := strconv.Atoi(record["user_id"])
+			if err := revokeFamily(c, userId, family); err != nil {
+				c.JSON(500, gin.H{"error": "Failed to revoke session"})
+				return
+			}
+		}
+	}
+
+	c.JSON(200, gin.H{"message": "Logged out successfully"})
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
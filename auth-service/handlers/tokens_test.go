@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// promotedUsers reports every user as staff, standing in for a role change
+// made after login.
+type promotedUsers struct {
+	*repository.MemoryUsers
+}
+
+func (r promotedUsers) GetByID(ctx context.Context, id int) (*types.User, error) {
+	user, err := r.MemoryUsers.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = []string{types.RoleCustomer, types.RoleStaff}
+	return user, nil
+}
+
+func postRefreshToken(handler gin.HandlerFunc, path, refreshToken string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST(path, handler)
+
+	body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func loginTokens(t *testing.T, h *UserHandler, email, password string) (string, string) {
+	t.Helper()
+	w := login(h, email, password)
+	if w.Code != 200 {
+		t.Fatalf("login: got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Token, resp.RefreshToken
+}
+
+func accessTokenRoles(t *testing.T, token string) []interface{} {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	roles, _ := claims["roles"].([]interface{})
+	return roles
+}
+
+func userFamilies(t *testing.T, userId int) []string {
+	t.Helper()
+	families, err := redis.RedisClient.SMembers(context.Background(), userFamiliesKey(userId)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families
+}
+
+func TestRefreshReloadsRoles(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	createTestUser(t, users, "ana@example.com", "secret123", true)
+	_, refreshToken := loginTokens(t, h, "ana@example.com", "secret123")
+
+	h.users = promotedUsers{users}
+	w := postRefreshToken(h.Refresh, "/refresh", refreshToken)
+	if w.Code != 200 {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	roles := accessTokenRoles(t, resp.Token)
+	if len(roles) != 2 || roles[1] != types.RoleStaff {
+		t.Fatalf("roles = %v, want the reloaded staff role", roles)
+	}
+}
+
+func TestRefreshForDeletedUserRevokesFamily(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	user := createTestUser(t, users, "ana@example.com", "secret123", true)
+	_, refreshToken := loginTokens(t, h, "ana@example.com", "secret123")
+
+	h.users = repository.NewMemoryUserRepository()
+	if w := postRefreshToken(h.Refresh, "/refresh", refreshToken); w.Code != 401 {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	if families := userFamilies(t, user.Id); len(families) != 0 {
+		t.Fatalf("families = %v, want none", families)
+	}
+}
+
+func TestRefreshTokenReuseRemovesFamily(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	user := createTestUser(t, users, "ana@example.com", "secret123", true)
+	_, refreshToken := loginTokens(t, h, "ana@example.com", "secret123")
+
+	if w := postRefreshToken(h.Refresh, "/refresh", refreshToken); w.Code != 200 {
+		t.Fatalf("first refresh: got %d: %s", w.Code, w.Body)
+	}
+	if w := postRefreshToken(h.Refresh, "/refresh", refreshToken); w.Code != 401 {
+		t.Fatalf("reuse: got %d: %s", w.Code, w.Body)
+	}
+	if families := userFamilies(t, user.Id); len(families) != 0 {
+		t.Fatalf("families = %v, want none after reuse", families)
+	}
+}
+
+func TestLogoutRemovesFamily(t *testing.T) {
+	h, users := newTestUserHandler(t)
+	user := createTestUser(t, users, "ana@example.com", "secret123", true)
+	_, first := loginTokens(t, h, "ana@example.com", "secret123")
+	loginTokens(t, h, "ana@example.com", "secret123")
+
+	if w := postRefreshToken(Logout, "/logout", first); w.Code != 200 {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	if families := userFamilies(t, user.Id); len(families) != 1 {
+		t.Fatalf("families = %v, want only the other session", families)
+	}
+	if w := postRefreshToken(h.Refresh, "/refresh", first); w.Code != 401 {
+		t.Fatalf("refresh after logout: got %d: %s", w.Code, w.Body)
+	}
+}
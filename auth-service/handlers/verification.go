@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/auth-service/kafka"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+)
+
+// Verification links are valid for a day.
+const verificationTokenTTL = 24 * time.Hour
+
+// Resending is limited per address: a burst of 3, then one every 20 minutes.
+const (
+	resendBurst        = 3
+	resendRefillPerSec = 1.0 / (20 * 60)
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// verificationSecret reads EMAIL_VERIFICATION_SECRET, falling back to
+// JWT_SECRET_KEY. It's read on every call because the .env file is only
+// loaded once main runs.
+func verificationSecret() []byte {
+	if s := os.Getenv("EMAIL_VERIFICATION_SECRET"); s != "" {
+		return []byte(s)
+	}
+	return []byte(os.Getenv("JWT_SECRET_KEY"))
+}
+
+// CheckVerificationSecret fails when no secret is configured, since an
+// empty HMAC key would let anyone forge verification links.
+func CheckVerificationSecret() error {
+	if len(verificationSecret()) == 0 {
+		return errors.New("EMAIL_VERIFICATION_SECRET or JWT_SECRET_KEY must be set")
+	}
+	return nil
+}
+
+// verificationToken returns a stateless token of the form
+// base64(userId|email|expiry).base64(hmac), so nothing has to be stored until
+// the link is used.
+func verificationToken(userId int, email string, expires time.Time) string {
+	payload := fmt.Sprintf("%d|%s|%d", userId, strings.ToLower(email), expires.Unix())
+	mac := hmac.New(sha256.New, verificationSecret())
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func parseVerificationToken(token string) (int, string, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", errInvalidVerificationToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	secret := verificationSecret()
+	if len(secret) == 0 {
+		return 0, "", errInvalidVerificationToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 {
+		return 0, "", errInvalidVerificationToken
+	}
+	userId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, "", errInvalidVerificationToken
+	}
+	return userId, parts[1], nil
+}
+
+// appBaseURL is where links in emails point. It defaults to the local gateway.
+func appBaseURL() string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// verificationLink points at the public gateway route, which forwards to
+// VerifyEmail.
+func verificationLink(token string) string {
+	return appBaseURL() + "/api/verify-email?token=" + url.QueryEscape(token)
+}
+
+func sendVerificationEmail(ctx context.Context, user *types.User) error {
+	token := verificationToken(user.Id, user.Email, time.Now().Add(verificationTokenTTL))
+	return kafka.ProduceEmailEvent(ctx, events.EmailRequested{
+		Kind:  events.EmailVerification,
+		Email: user.Email,
+		Name:  user.Name,
+		Link:  verificationLink(token),
+	})
+}
+
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userId, email, err := parseVerificationToken(c.Query("token"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Matching on the email too means a link stops working if the address
+	// on the account changes.
+	user, err := h.users.GetByID(ctx, userId)
+	if err == nil && normalizeEmail(user.Email) != email {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(400, gin.H{"error": errInvalidVerificationToken.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to query user",
+			"details": err.Error()})
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(200, gin.H{"message": "Email already verified"})
+		return
+	}
+
+	err = h.users.SetEmailVerified(ctx, user.Id, true)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to verify email",
+			"details": err.Error()})
+		return
+	}
+
+	// The welcome email now goes out once the address is confirmed.
+	if err := kafka.ProduceEmail(ctx, user.Email, user.Name, user.CreatedAt); err != nil {
+		logging.FromContext(ctx).Error("Failed to send welcome email", "user_id", user.Id, "error", err)
+	}
+
+	c.JSON(200, gin.H{"message": "Email verified successfully"})
+}
+
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	allowed, remaining, err := redis.TakeToken(ctx, "verify_resend:"+email, resendBurst, resendRefillPerSec, 1)
+	if err != nil {
+		c.JSON(503, gin.H{"error": "Rate limiter unavailable"})
+		return
+	}
+	if !allowed {
+		retryAfter := redis.RetryAfter(remaining, resendRefillPerSec)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.JSON(429, gin.H{"error": "Too many verification emails requested",
+			"retry_after": int(retryAfter.Seconds())})
+		return
+	}
+
+	// The response is the same whether or not the address exists, so this
+	// endpoint can't be used to discover accounts.
+	const response = "If the account exists and is unverified, a verification email has been sent"
+
+	user, err := h.users.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			logging.FromContext(ctx).Error("Failed to query user", "error", err)
+		}
+		c.JSON(200, gin.H{"message": response})
+		return
+	}
+	if !user.EmailVerified {
+		// A failure is only logged; an error response would reveal that
+		// the account exists
+		if err := sendVerificationEmail(ctx, user); err != nil {
+			logging.FromContext(ctx).Error("Failed to send verification email", "user_id", user.Id, "error", err)
+		}
+	}
+	c.JSON(200, gin.H{"message": response})
+}
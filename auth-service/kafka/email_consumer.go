@@ -2,56 +2,58 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"fmt"
 
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
 	"github.com/RohithBN/shared/utils"
-	"github.com/segmentio/kafka-go"
 )
 
-func ConsumeEmail() {
-	// Call the context version with a background context
-	ConsumeEmailWithContext(context.Background())
+// ConsumeEmailWithContext sends the emails published to EmailTopic until ctx
+// is done. Emails that fail to send are retried, then dead-lettered.
+func ConsumeEmailWithContext(ctx context.Context, b bus.Bus) error {
+	return bus.Consume(ctx, b, EmailTopic, emailGroup, handleEmailMessage, bus.RetryPolicyFromEnv())
 }
 
-func ConsumeEmailWithContext(ctx context.Context) error {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{"localhost:9092"},
-		Topic:   "email-topic",
-		GroupID: "email-group",
-	})
-	defer reader.Close()
-
-	for {
-		select {
-		case <-ctx.Done():
-			// Context was canceled, exit gracefully
-			log.Println("Email consumer shutting down...")
-			return nil
-		default:
-			m, err := reader.ReadMessage(ctx)
-			if err != nil {
-				log.Printf("Error reading message: %v", err)
-				continue
-			}
-
-			var event struct {
-				Email     string `json:"email"`
-				Name      string `json:"name"`
-				CreatedAt string `json:"createdAt"`
-			}
-
-			if err := json.Unmarshal(m.Value, &event); err != nil {
-				log.Printf("Error unmarshalling message: %v", err)
-				continue
-			}
-
-			log.Printf("Received message: %s", string(m.Value))
-			log.Printf("Email: %s, Name: %s, CreatedAt: %s", event.Email, event.Name, event.CreatedAt)
-
-			if err := utils.SendEmailAfterRegistration(event.Email, event.Name, event.CreatedAt); err != nil {
-				log.Printf("Error sending email: %v", err)
-			}
-		}
+const emailGroup = "email-group"
+
+func handleEmailMessage(ctx context.Context, m bus.Message) error {
+	var event events.EmailRequested
+	env, err := bus.DecodeEvent(m, &event)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("error unmarshalling message: %v", err))
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Info("Received message", "topic", m.Topic, "kind", event.Kind, "email", event.Email, "event_id", env.ID)
+
+	// Don't send the same email twice when a message is redelivered
+	if done, err := redis.EventProcessed(ctx, emailGroup, env.ID); err != nil {
+		return fmt.Errorf("error checking processed events: %v", err)
+	} else if done {
+		logger.Info("Skipping already processed event", "event_id", env.ID)
+		return nil
+	}
+
+	switch event.Kind {
+	case events.EmailWelcome:
+		err = utils.SendEmailAfterRegistration(event.Email, event.Name, event.CreatedAt)
+	case events.EmailVerification:
+		err = utils.SendVerificationEmail(event.Email, event.Name, event.Link)
+	case events.EmailPasswordReset:
+		err = utils.SendPasswordResetEmail(event.Email, event.Name, event.Link)
+	case events.EmailAccountLocked:
+		err = utils.SendAccountLockedEmail(event.Email, event.Name, event.LockedUntil)
+	default:
+		return bus.Permanent(fmt.Errorf("unknown email kind %q", event.Kind))
+	}
+	if err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+	if err := redis.MarkEventProcessed(ctx, emailGroup, env.ID); err != nil {
+		logger.Error("Failed to mark event processed", "event_id", env.ID, "error", err)
 	}
+	return nil
 }
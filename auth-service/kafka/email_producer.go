@@ -2,35 +2,36 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/repository"
 )
 
-var writer *kafka.Writer
+// EmailTopic carries events.EmailRequested to the email consumer.
+const EmailTopic = "email-topic"
 
-func InitKafkaWriter() {
-	writer = &kafka.Writer{
-		Addr:     kafka.TCP("localhost:9092"),
-		Topic:    "email-topic",
-		Balancer: &kafka.LeastBytes{},
-	}
+var outbox repository.Outbox
 
+// InitOutbox sets the outbox email events are stored in. The relay
+// publishes them once the transaction that stored them commits.
+func InitOutbox(o repository.Outbox) {
+	outbox = o
 }
 
-func ProduceEmail(email string, name string, createdAt string) error {
-	event := map[string]string{
-		"email":     email,
-		"name":      name,
-		"createdAt": createdAt,
-	}
-
-	payload, _ := json.Marshal(event)
+func ProduceEmail(ctx context.Context, email string, name string, createdAt string) error {
+	return ProduceEmailEvent(ctx, events.EmailRequested{
+		Kind:      events.EmailWelcome,
+		Email:     email,
+		Name:      name,
+		CreatedAt: createdAt,
+	})
+}
 
-	return writer.WriteMessages(context.Background(),
-		kafka.Message{
-			Key:   []byte(email),
-			Value: payload,
-		},
-)
+func ProduceEmailEvent(ctx context.Context, event events.EmailRequested) error {
+	msg, err := bus.NewEventMessage(ctx, EmailTopic, event.Email, &event)
+	if err != nil {
+		return err
+	}
+	return outbox.Add(ctx, EmailTopic, msg)
 }
@@ -5,13 +5,22 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/RohithBN/auth-service/handlers"
 	"github.com/RohithBN/auth-service/kafka"
+	authmigrations "github.com/RohithBN/auth-service/migrations"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/metrics"
+	"github.com/RohithBN/shared/migrate"
+	"github.com/RohithBN/shared/outbox"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/tracing"
 	"github.com/RohithBN/shared/utils"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -21,6 +30,15 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	logging.Init("auth-service")
+	events.SetSource("auth-service")
+
+	shutdownTracing, err := tracing.Init(context.Background(), "auth-service")
+	if err != nil {
+		log.Fatalf("Error initializing tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Then initialize database
 	db, err := utils.ConnectDB()
 	if err != nil {
@@ -28,31 +46,112 @@ func main() {
 	}
 	defer db.Close()
 
-	if err := handlers.InitDB(); err != nil {
-		log.Fatalf("Error initializing database: %v\n", err)
+	// Schema migrations are embedded in the binary
+	migrations, err := migrate.Load(authmigrations.FS, ".")
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+	migrator := migrate.New(db, migrations)
+
+	// "auth-service migrate <command>" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if os.Getenv("DB_MIGRATE_ON_START") != "false" {
+		if _, err := migrator.Up(context.Background(), 0); err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+	}
+
+	// JWT_SIGNING_ALG selects RS256 (default) or ES256 keys, or HS256 for local development
+	if err := handlers.InitKeys(); err != nil {
+		log.Fatalf("Error loading signing keys: %v", err)
 	}
 
-	// Initialize Kafka components
-	kafka.InitKafkaWriter()
+	// Verification links are HMAC-signed
+	if err := handlers.CheckVerificationSecret(); err != nil {
+		log.Fatalf("Error loading verification secret: %v", err)
+	}
+
+	// TOTP secrets are encrypted at rest
+	if err := handlers.CheckMFAEncryptionKey(); err != nil {
+		log.Fatalf("Error loading MFA encryption key: %v", err)
+	}
+
+	// Refresh tokens and the revocation list live in Redis
+	if err := redis.ConnectRedis(); err != nil {
+		log.Fatalf("Error connecting to Redis: %v", err)
+	}
+
+	// Email events are stored in the outbox table and relayed to Kafka
+	msgBus := bus.NewKafka(bus.BrokersFromEnv()...)
+	defer msgBus.Close()
+	emailOutbox := repository.NewPostgresOutbox(db)
+	kafka.InitOutbox(emailOutbox)
 
-	// Create a cancelable context for the consumer
+	// Create a cancelable context for the consumer and the relay
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	go outbox.NewRelay(emailOutbox, msgBus).Run(ctx)
+
 	// Start consumer with context
 	go func() {
-		if err := kafka.ConsumeEmailWithContext(ctx); err != nil {
+		if err := kafka.ConsumeEmailWithContext(ctx, msgBus); err != nil {
 			log.Printf("Error starting Kafka consumer: %v", err)
 		}
 	}()
 
 	// Setup router
-	router := gin.Default()
+	router := logging.NewRouter("auth-service")
 	router.Use(metrics.PrometheusMiddleware())
 
+	// Login lockouts are keyed on the client IP, which the gateway appends to
+	// X-Forwarded-For. Only trust that header from the gateway's addresses.
+	trustedProxies := []string{"127.0.0.1", "::1"}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		trustedProxies = strings.Split(v, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	userHandler := handlers.NewUserHandler(
+		repository.NewPostgresUserRepository(db),
+		repository.NewPostgresTransactor(db),
+	)
+	mfaHandler := handlers.NewMFAHandler(repository.NewPostgresMFARepository(db))
+	addressHandler := handlers.NewAddressHandler(repository.NewPostgresAddressRepository(db))
+
 	metrics.RegisterMetricsEndpoint(router)
-	router.POST("/register", handlers.Register)
-	router.POST("/login", handlers.Login)
+	metrics.RegisterHealthEndpoint(router)
+	router.POST("/register", userHandler.Register)
+	router.POST("/login", userHandler.Login)
+	router.POST("/refresh", userHandler.Refresh)
+	router.POST("/logout", handlers.Logout)
+	router.GET("/verify-email", userHandler.VerifyEmail)
+	router.POST("/verify-email/resend", userHandler.ResendVerification)
+	router.POST("/password/forgot", userHandler.ForgotPassword)
+	router.POST("/password/reset", userHandler.ResetPassword)
+	router.POST("/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+	router.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+	router.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+	router.POST("/mfa/totp/verify", mfaHandler.VerifyTOTP)
+	router.GET("/.well-known/jwks.json", handlers.JWKS)
+
+	// Profile and address book, for the user the gateway authenticated
+	router.GET("/me", userHandler.GetProfile)
+	router.PATCH("/me", userHandler.UpdateProfile)
+	router.POST("/me/password", userHandler.ChangePassword)
+	router.GET("/me/addresses", addressHandler.ListAddresses)
+	router.POST("/me/addresses", addressHandler.CreateAddress)
+	router.GET("/me/addresses/:id", addressHandler.GetAddress)
+	router.PUT("/me/addresses/:id", addressHandler.UpdateAddress)
+	router.DELETE("/me/addresses/:id", addressHandler.DeleteAddress)
+	router.POST("/me/addresses/:id/default", addressHandler.SetDefaultAddress)
 
 	// Handle shutdown gracefully
 	go func() {
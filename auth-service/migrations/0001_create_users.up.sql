@@ -0,0 +1,9 @@
+-- Baseline: the table Register and Login have always used. IF NOT EXISTS
+-- lets this run against databases created before migrations existed.
+CREATE TABLE IF NOT EXISTS USERS (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
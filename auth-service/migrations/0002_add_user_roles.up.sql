@@ -0,0 +1 @@
+ALTER TABLE USERS ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{customer}';
@@ -0,0 +1,4 @@
+-- Accounts that existed before verification was introduced are treated as
+-- verified; new accounts start unverified.
+ALTER TABLE USERS ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE USERS ALTER COLUMN email_verified SET DEFAULT false;
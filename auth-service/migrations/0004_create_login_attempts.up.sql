@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    user_id INT REFERENCES USERS(id) ON DELETE SET NULL,
+    ip TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason TEXT NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email, attempted_at);
+
+-- Logins, resets and resends look users up case-insensitively
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON USERS (LOWER(email));
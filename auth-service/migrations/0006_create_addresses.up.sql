@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES USERS(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('shipping', 'billing')),
+    label TEXT NOT NULL DEFAULT '',
+    recipient TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL,
+    country TEXT NOT NULL,
+    phone TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- At most one default address per user and kind
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_one_default_idx ON addresses (user_id, kind) WHERE is_default;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_lower_email_idx;
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON USERS (LOWER(email));
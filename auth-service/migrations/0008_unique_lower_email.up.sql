@@ -0,0 +1,6 @@
+-- Emails are stored normalised and unique regardless of case, matching the
+-- case-insensitive lookups. This fails if two accounts differ only by the
+-- case of their email; merge those first.
+UPDATE USERS SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+DROP INDEX IF EXISTS users_lower_email_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS users_lower_email_idx ON USERS (LOWER(email));
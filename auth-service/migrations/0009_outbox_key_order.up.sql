@@ -0,0 +1,3 @@
+-- The relay only claims the oldest unsent record of each key, which looks
+-- up earlier records by topic and key.
+CREATE INDEX IF NOT EXISTS outbox_pending_key_idx ON outbox (topic, key, id) WHERE sent_at IS NULL;
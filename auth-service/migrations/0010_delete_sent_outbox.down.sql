@@ -0,0 +1 @@
+-- Deleted records can't be restored.
@@ -0,0 +1,3 @@
+-- The relay now deletes records once they are published. Remove the ones it
+-- used to keep, which include verification and password reset links.
+DELETE FROM outbox WHERE sent_at IS NOT NULL;
@@ -0,0 +1,9 @@
+// Package migrations holds the auth database schema. Files are applied in
+// version order by shared/migrate; add new changes as the next
+// <version>_<name>.up.sql / .down.sql pair rather than editing old ones.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
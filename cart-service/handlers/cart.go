@@ -2,19 +2,33 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/RohithBN/cart-service/kafka"
+	"github.com/RohithBN/shared/repository"
 	"github.com/RohithBN/shared/types"
-	"github.com/RohithBN/shared/utils"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AddToCart(c *gin.Context) {
+type CartHandler struct {
+	carts    repository.CartRepository
+	products repository.ProductRepository
+	// tx stores cart changes together with the stock event.
+	tx repository.Transactor
+	// produceCartAddItem tells product-service to take the items out of
+	// stock. Tests replace it to avoid needing an outbox.
+	produceCartAddItem func(ctx context.Context, quantity int, productId string, userId int) error
+}
+
+func NewCartHandler(carts repository.CartRepository, products repository.ProductRepository, tx repository.Transactor) *CartHandler {
+	return &CartHandler{carts: carts, products: products, tx: tx, produceCartAddItem: kafka.ProduceCartAddItem}
+}
+
+func (h *CartHandler) AddToCart(c *gin.Context) {
 	// get userId from token
 	var quantity int
 	if err := c.BindJSON(&quantity); err != nil {
@@ -46,23 +60,23 @@ func AddToCart(c *gin.Context) {
 	}
 
 	// get product from db
-	var product types.Product
-	productCollection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
-	err = productCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&product)
+	product, err := h.products.Get(ctx, objectId)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "Product not found"})
 		return
 	}
 
-	cartCollection := utils.MongoDB.Collection("carts")
-	var cart types.Cart
-	err = cartCollection.FindOne(ctx, bson.M{"userid": user_id}).Decode(&cart)
+	cart, err := h.carts.Get(ctx, user_id)
 	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(500, gin.H{"error": "Failed to fetch cart"})
+			return
+		}
 		var products []types.Product
 		for i := 0; i < quantity; i++ {
-			products = append(products, product)
+			products = append(products, *product)
 		}
 		// cart doesn't exist, create new
 		newCart := types.Cart{
@@ -70,12 +84,11 @@ func AddToCart(c *gin.Context) {
 			Products:   products,
 			TotalPrice: product.Price * float64(quantity),
 		}
-		_, err := cartCollection.InsertOne(ctx, newCart)
+		err := h.saveCart(ctx, &newCart, quantity, productId)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Failed to create cart"})
 			return
 		}
-		// send Message to Kafka
 
 		c.JSON(200, gin.H{"message": "Product added to new cart", "cart": newCart})
 		return
@@ -88,32 +101,31 @@ func AddToCart(c *gin.Context) {
 	}
 	if product.Stock > 0 {
 		for i := 0; i < quantity; i++ {
-			cart.Products = append(cart.Products, product)
+			cart.Products = append(cart.Products, *product)
 		}
 		cart.TotalPrice += product.Price * float64(quantity)
-		_, err = cartCollection.UpdateOne(
-			ctx,
-			bson.M{"userid": user_id},
-			bson.M{"$set": bson.M{
-				"products":   cart.Products,
-				"totalprice": cart.TotalPrice,
-			}},
-		)
+		err = h.saveCart(ctx, cart, quantity, productId)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Failed to update cart"})
 			return
 		}
 	}
-	// update product stock
-	if err := kafka.ProduceCartAddItem(quantity, productId, user_id); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to produce message to Kafka"})
-		return
-	}
 
 	c.JSON(200, gin.H{"message": "Product added to cart", "cart": cart})
 }
 
-func GetCart(c *gin.Context) {
+// saveCart stores cart and the event that takes the added items out of
+// stock in one transaction.
+func (h *CartHandler) saveCart(ctx context.Context, cart *types.Cart, quantity int, productId string) error {
+	return h.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := h.carts.Save(ctx, cart); err != nil {
+			return err
+		}
+		return h.produceCartAddItem(ctx, quantity, productId, cart.UserId)
+	})
+}
+
+func (h *CartHandler) GetCart(c *gin.Context) {
 	userIdStr := c.GetHeader("X-User-ID")
 	if userIdStr == "" {
 		c.JSON(401, gin.H{"error": "User ID not found"})
@@ -125,11 +137,9 @@ func GetCart(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "Invalid user ID format"})
 		return
 	}
-	cartCollection := utils.MongoDB.Collection("carts")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
-	var cart types.Cart
-	err = cartCollection.FindOne(ctx, bson.M{"userid": user_id}).Decode(&cart)
+	cart, err := h.carts.Get(ctx, user_id)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "Cart not found"})
 		return
@@ -137,7 +147,7 @@ func GetCart(c *gin.Context) {
 	c.JSON(200, gin.H{"cart": cart})
 }
 
-func DeleteFromCart(c *gin.Context) {
+func (h *CartHandler) DeleteFromCart(c *gin.Context) {
 	userIdStr := c.GetHeader("X-User-ID")
 	if userIdStr == "" {
 		c.JSON(401, gin.H{"error": "User ID not found"})
@@ -156,12 +166,10 @@ func DeleteFromCart(c *gin.Context) {
 		return
 	}
 
-	cartCollection := utils.MongoDB.Collection("carts")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	var cart types.Cart
-	err = cartCollection.FindOne(ctx, bson.M{"userid": user_id}).Decode(&cart)
+	cart, err := h.carts.Get(ctx, user_id)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "Cart not found"})
 		return
@@ -185,14 +193,7 @@ func DeleteFromCart(c *gin.Context) {
 	cart.Products = updatedProducts
 	cart.TotalPrice = totalPrice
 
-	_, err = cartCollection.UpdateOne(
-		ctx,
-		bson.M{"userid": user_id},
-		bson.M{"$set": bson.M{
-			"products":   cart.Products,
-			"totalprice": cart.TotalPrice,
-		}},
-	)
+	err = h.carts.Save(ctx, cart)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update cart"})
 		return
@@ -201,7 +202,7 @@ func DeleteFromCart(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Product removed from cart", "cart": cart})
 }
 
-func ClearCart(c *gin.Context) {
+func (h *CartHandler) ClearCart(c *gin.Context) {
 	userIdStr := c.GetHeader("X-User-ID")
 	if userIdStr == "" {
 		c.JSON(401, gin.H{"error": "User ID not found"})
@@ -214,11 +215,10 @@ func ClearCart(c *gin.Context) {
 		return
 	}
 
-	cartCollection := utils.MongoDB.Collection("carts")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	_, err = cartCollection.DeleteOne(ctx, bson.M{"userid": user_id})
+	err = h.carts.Delete(ctx, user_id)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to clear cart"})
 		return
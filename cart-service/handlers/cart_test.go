@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+)
+
+type stockEvent struct {
+	quantity  int
+	productId string
+	userId    int
+}
+
+func newTestCartHandler(t *testing.T) (*CartHandler, *repository.MemoryCarts, *repository.MemoryProducts, *[]stockEvent) {
+	t.Helper()
+	carts := repository.NewMemoryCartRepository()
+	products := repository.NewMemoryProductRepository()
+	h := NewCartHandler(carts, products, repository.MemoryTransactor{})
+
+	var produced []stockEvent
+	h.produceCartAddItem = func(ctx context.Context, quantity int, productId string, userId int) error {
+		produced = append(produced, stockEvent{quantity, productId, userId})
+		return nil
+	}
+	return h, carts, products, &produced
+}
+
+func addToCart(h *CartHandler, userId, productId, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/cart/:productId", h.AddToCart)
+
+	req := httptest.NewRequest(http.MethodPost, "/cart/"+productId, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userId != "" {
+		req.Header.Set("X-User-ID", userId)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAddToCartCreatesCart(t *testing.T) {
+	h, carts, products, produced := newTestCartHandler(t)
+	product := types.Product{Name: "Mug", Price: 4.5, Stock: 10}
+	products.Create(context.Background(), &product)
+
+	w := addToCart(h, "7", product.ID.Hex(), "2")
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	cart, err := carts.Get(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("cart not saved: %v", err)
+	}
+	if len(cart.Products) != 2 || cart.TotalPrice != 9 {
+		t.Errorf("cart = %d products, total %v; want 2 products, total 9", len(cart.Products), cart.TotalPrice)
+	}
+	want := []stockEvent{{2, product.ID.Hex(), 7}}
+	if len(*produced) != 1 || (*produced)[0] != want[0] {
+		t.Errorf("stock events = %v, want %v", *produced, want)
+	}
+}
+
+func TestAddToCartAppendsToExistingCart(t *testing.T) {
+	h, carts, products, produced := newTestCartHandler(t)
+	product := types.Product{Name: "Mug", Price: 4.5, Stock: 10}
+	products.Create(context.Background(), &product)
+	carts.Save(context.Background(), &types.Cart{UserId: 7, Products: []types.Product{product}, TotalPrice: 4.5})
+
+	w := addToCart(h, "7", product.ID.Hex(), "1")
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Cart types.Cart `json:"cart"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Cart.Products) != 2 || resp.Cart.TotalPrice != 9 {
+		t.Errorf("cart = %d products, total %v; want 2 products, total 9", len(resp.Cart.Products), resp.Cart.TotalPrice)
+	}
+	if len(*produced) != 1 {
+		t.Errorf("got %d stock events, want 1", len(*produced))
+	}
+}
+
+func TestAddToCartOutOfStock(t *testing.T) {
+	h, carts, products, produced := newTestCartHandler(t)
+	product := types.Product{Name: "Mug", Price: 4.5}
+	products.Create(context.Background(), &product)
+	carts.Save(context.Background(), &types.Cart{UserId: 7})
+
+	if w := addToCart(h, "7", product.ID.Hex(), "1"); w.Code != 400 {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if len(*produced) != 0 {
+		t.Errorf("stock event produced for a rejected request")
+	}
+}
+
+func TestAddToCartRejectsBadInput(t *testing.T) {
+	h, _, products, produced := newTestCartHandler(t)
+	product := types.Product{Name: "Mug", Price: 4.5, Stock: 10}
+	products.Create(context.Background(), &product)
+
+	tests := []struct {
+		name      string
+		userId    string
+		productId string
+		body      string
+		want      int
+	}{
+		{"zero quantity", "7", product.ID.Hex(), "0", 400},
+		{"missing user", "", product.ID.Hex(), "1", 401},
+		{"invalid product ID", "7", "not-an-id", "1", 400},
+		{"unknown product", "7", "0123456789abcdef01234567", "1", 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := addToCart(h, tt.userId, tt.productId, tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+	if len(*produced) != 0 {
+		t.Errorf("stock events produced for rejected requests: %v", *produced)
+	}
+}
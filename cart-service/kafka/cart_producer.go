@@ -2,40 +2,32 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 
-	"github.com/RohithBN/shared/metrics"
-	"github.com/segmentio/kafka-go"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/repository"
 )
 
-var writer *kafka.Writer
+// CartAddItemTopic tells product-service to take items out of stock.
+const CartAddItemTopic = "cart-add-item-topic"
 
-func InitKafkaWriter() {
-	writer = &kafka.Writer{
-		Addr:     kafka.TCP("localhost:9092"),
-		Topic:    "cart-add-item-topic",
-		Balancer: &kafka.LeastBytes{},
-	}
+var outbox repository.Outbox
+
+// InitOutbox sets the outbox cart events are stored in. The relay
+// publishes them once the transaction that stored them commits.
+func InitOutbox(o repository.Outbox) {
+	outbox = o
 }
 
-func ProduceCartAddItem(quantity int, productId string, userId int) error {
-	event := map[string]interface{}{
-		"quantity":  quantity,
-		"productId": productId,
-		"userId":    userId,
+func ProduceCartAddItem(ctx context.Context, quantity int, productId string, userId int) error {
+	event := events.CartItemAdded{
+		ProductId: productId,
+		Quantity:  quantity,
+		UserId:    userId,
 	}
-
-	payload, _ := json.Marshal(event)
-
-
-	err:= writer.WriteMessages(context.Background(), kafka.Message{
-		Key:   []byte(productId),
-		Value: payload,
-	})
+	msg, err := bus.NewEventMessage(ctx, CartAddItemTopic, productId, &event)
 	if err != nil {
-		metrics.KafkaOperations.WithLabelValues("cart-add-item-topic","produce","error").Inc()
-		return err;
+		return err
 	}
-	metrics.KafkaOperations.WithLabelValues("cart-add-item-topic","produce","success").Inc()
-	return nil
+	return outbox.Add(ctx, CartAddItemTopic, msg)
 }
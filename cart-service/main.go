@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/RohithBN/cart-service/handlers"
 	"github.com/RohithBN/cart-service/kafka"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/metrics"
+	"github.com/RohithBN/shared/outbox"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/tracing"
 	"github.com/RohithBN/shared/utils"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -16,23 +22,46 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	logging.Init("cart-service")
+	events.SetSource("cart-service")
+
+	shutdownTracing, err := tracing.Init(context.Background(), "cart-service")
+	if err != nil {
+		log.Fatalf("Error initializing tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	if err := utils.ConnectMongoDB(); err != nil {
 		log.Fatalf("Error connecting to MongoDB: %v", err)
 	}
 
-	//inititalise kafka writer
-	kafka.InitKafkaWriter()
+	// Stock updates are stored in the outbox with the cart change and
+	// relayed to Kafka
+	msgBus := bus.NewKafka(bus.BrokersFromEnv()...)
+	defer msgBus.Close()
+	cartOutbox := repository.NewMongoOutbox(utils.MongoDB)
+	kafka.InitOutbox(cartOutbox)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outbox.NewRelay(cartOutbox, msgBus).Run(ctx)
 
-	router := gin.Default()
+	router := logging.NewRouter("cart-service")
 
 	router.Use(metrics.PrometheusMiddleware())
 
 	metrics.RegisterMetricsEndpoint(router)
+	metrics.RegisterHealthEndpoint(router)
 
 	// Routes aligned with gateway
-	router.POST("/cart/:productId", handlers.AddToCart)
-	router.GET("/cart", handlers.GetCart)
-	router.DELETE("/cart/:productId", handlers.DeleteFromCart)
+	cartHandler := handlers.NewCartHandler(
+		repository.NewMongoCartRepository(utils.MongoDB),
+		repository.NewMongoProductRepository(utils.MongoDB),
+		repository.NewMongoTransactor(utils.MongoDB),
+	)
+	router.POST("/cart/:productId", cartHandler.AddToCart)
+	router.GET("/cart", cartHandler.GetCart)
+	router.DELETE("/cart/:productId", cartHandler.DeleteFromCart)
 
 	log.Printf("Cart service starting on port 8083")
 	router.Run(":8083")
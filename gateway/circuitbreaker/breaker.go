@@ -0,0 +1,210 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/RohithBN/gateway/registry"
+	"github.com/RohithBN/shared/metrics"
+)
+
+type State int
+
+// Gauge values exported as gateway_circuit_breaker_state.
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Settings configure a single breaker.
+type Settings struct {
+	// FailureThreshold consecutive failures open the breaker.
+	FailureThreshold int
+	// CoolDown is how long the breaker stays open before probing.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of probe requests allowed while
+	// half-open; all of them must succeed to close the breaker again.
+	HalfOpenRequests int
+}
+
+func settingsFrom(cfg registry.CircuitBreakerConfig) Settings {
+	return Settings{
+		FailureThreshold: cfg.Threshold(),
+		CoolDown:         cfg.CoolDownDuration(),
+		HalfOpenRequests: cfg.HalfOpenLimit(),
+	}
+}
+
+// Breaker is a closed/open/half-open circuit breaker for one upstream.
+type Breaker struct {
+	name string
+
+	mu        sync.Mutex
+	settings  Settings
+	state     State
+	failures  int
+	openedAt  time.Time
+	probes    int // requests admitted while half-open
+	successes int // successful probes while half-open
+}
+
+func New(name string, settings Settings) *Breaker {
+	b := &Breaker{name: name, settings: settings}
+	b.export()
+	return b
+}
+
+// Allow reports whether a request may be sent upstream. Every allowed
+// request must be followed by exactly one call to Record or Cancel.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		if time.Since(b.openedAt) < b.settings.CoolDown {
+			return ErrOpen
+		}
+		b.setState(HalfOpen)
+	}
+	if b.state == HalfOpen {
+		if b.probes >= b.settings.HalfOpenRequests {
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record reports the outcome of a request admitted by Allow.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case HalfOpen:
+		if !success {
+			b.trip()
+			return
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.trip()
+		}
+	}
+}
+
+// Cancel releases a request admitted by Allow without recording an outcome,
+// for requests the client abandoned. It frees the probe slot when half-open.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// RetryAfter returns how long until the breaker will admit a probe.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Open {
+		return 0
+	}
+	return b.settings.CoolDown - time.Since(b.openedAt)
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) configure(settings Settings) {
+	b.mu.Lock()
+	b.settings = settings
+	b.mu.Unlock()
+}
+
+func (b *Breaker) trip() {
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+// setState must be called with b.mu held.
+func (b *Breaker) setState(state State) {
+	if b.state != state {
+		log.Printf("Circuit breaker for %s: %s -> %s", b.name, b.state, state)
+	}
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	b.export()
+}
+
+func (b *Breaker) export() {
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(b.state))
+}
+
+var (
+	breakers   = map[string]*Breaker{}
+	breakersMu sync.Mutex
+)
+
+// Configure creates or updates one breaker per registered service and keeps
+// them in sync with registry reloads.
+func Configure(reg *registry.Registry) {
+	update(reg.Services())
+	reg.OnReload(update)
+}
+
+// Get returns the breaker for service, creating one with default settings
+// if the service is not configured.
+func Get(service string) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[service]
+	if !ok {
+		b = New(service, settingsFrom(registry.CircuitBreakerConfig{}))
+		breakers[service] = b
+	}
+	return b
+}
+
+func update(services map[string]registry.ServiceConfig) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	for name, svc := range services {
+		settings := settingsFrom(svc.CircuitBreaker)
+		if b, ok := breakers[name]; ok {
+			b.configure(settings)
+			continue
+		}
+		breakers[name] = New(name, settings)
+	}
+}
@@ -1,44 +1,44 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/RohithBN/gateway/circuitbreaker"
+	"github.com/RohithBN/gateway/loadbalancer"
+	"github.com/RohithBN/gateway/registry"
+	"github.com/RohithBN/shared/metrics"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-var serviceURLs = map[string]string{
-	"auth":     "http://localhost:8081",
-	"products": "http://localhost:8082",
-	"cart":     "http://localhost:8083",
-	"orders":   "http://localhost:8084",
-}
+// transport creates a client span per upstream attempt and injects the W3C
+// trace context into the proxied request.
+var transport = otelhttp.NewTransport(http.DefaultTransport)
 
 func ProxyHandler(service, path string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get service URL
-		serviceURL, exists := serviceURLs[service]
+		// Get the instance pool for the service
+		pool, exists := loadbalancer.Pools.Get(service)
 		if !exists {
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Service not found"})
-			return
-		}
-
-		// Create the target URL
-		target, err := url.Parse(fmt.Sprintf("%s%s", serviceURL, path))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse target URL"})
+			writeError(c.Writer, http.StatusBadGateway, "service_not_found", service, "Service not found")
 			return
 		}
+		cfg, _ := registry.Default.Service(service)
+		breaker := circuitbreaker.Get(service)
 
-		// Create reverse proxy
-		proxy := httputil.NewSingleHostReverseProxy(target)
-
-		// Update the request URL
-		c.Request.URL.Host = target.Host
-		c.Request.URL.Scheme = target.Scheme
 		c.Request.Header.Set("X-Forwarded-Host", c.Request.Header.Get("Host"))
 
 		// Forward user information if authenticated
@@ -48,29 +48,204 @@ func ProxyHandler(service, path string) gin.HandlerFunc {
 
 		}
 
-		originalDirector := proxy.Director
-		proxy.Director = func(req *http.Request) {
-			originalDirector(req)
+		attempts := cfg.Retry.Attempts()
+		if !isIdempotent(c.Request.Method) {
+			attempts = 1
+		}
 
-			modifiedPath := path
+		// Non-retryable requests are streamed straight to the client
+		if attempts == 1 {
+			if err := breaker.Allow(); err != nil {
+				circuitOpen(c, breaker, service)
+				return
+			}
+			record(breaker, forward(c, pool, cfg, service, path, c.Writer))
+			return
+		}
+
+		// Retryable requests are buffered so the body can be replayed and a
+		// failed attempt never reaches the client
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				writeError(c.Writer, http.StatusBadRequest, "invalid_body", service, "Failed to read request body")
+				return
+			}
+		}
 
-			// used for replacing :productId with the actual product ID 
-			for _, param := range c.Params {
-				modifiedPath = strings.Replace(modifiedPath, ":"+param.Key, param.Value, 1)
+		var last *bufferedResponse
+		for attempt := 0; attempt < attempts; attempt++ {
+			if attempt > 0 {
+				metrics.UpstreamRetries.WithLabelValues(service).Inc()
+				select {
+				case <-c.Request.Context().Done():
+					// The client is gone, nobody is waiting for a retry
+					return
+				case <-time.After(backoff(cfg.Retry, attempt)):
+				}
+			}
+			if breaker.Allow() != nil {
+				break
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			resp := newBufferedResponse()
+			result := forward(c, pool, cfg, service, path, resp)
+			record(breaker, result)
+			if result == loadbalancer.Canceled {
+				return
+			}
+			last = resp
+			if result == loadbalancer.Succeeded {
+				break
 			}
+		}
+
+		// The breaker rejected the first attempt
+		if last == nil {
+			circuitOpen(c, breaker, service)
+			return
+		}
+		last.copyTo(c.Writer)
+	}
+}
+
+// circuitOpen writes the error envelope for a request the breaker rejected.
+func circuitOpen(c *gin.Context, breaker *circuitbreaker.Breaker, service string) {
+	retryAfter := int(math.Ceil(breaker.RetryAfter().Seconds()))
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	writeError(c.Writer, http.StatusServiceUnavailable, "circuit_open", service, "Service temporarily unavailable")
+}
+
+// record reports the result of a request admitted by the breaker.
+func record(breaker *circuitbreaker.Breaker, result loadbalancer.Result) {
+	if result == loadbalancer.Canceled {
+		breaker.Cancel()
+		return
+	}
+	breaker.Record(result == loadbalancer.Succeeded)
+}
+
+// forward proxies one attempt to an instance from pool and reports whether
+// it succeeded, failed (connection error, timeout or 5xx) or was canceled by
+// the client.
+func forward(c *gin.Context, pool *loadbalancer.Pool, cfg registry.ServiceConfig, service, path string, w http.ResponseWriter) loadbalancer.Result {
+	// X-User-ID is set by AuthMiddleware and keys consistent hashing
+	instance, err := pool.Acquire(c.GetHeader("X-User-ID"))
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "no_healthy_upstream", service, "No healthy upstream available")
+		return loadbalancer.Failed
+	}
+	result := loadbalancer.Succeeded
+	defer func() { pool.Release(instance, result) }()
+
+	target := instance.URL
 
-			req.URL.Path = modifiedPath
+	// Create reverse proxy
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+
+	originalDirector := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		originalDirector(req)
+
+		modifiedPath := path
+
+		// used for replacing :productId with the actual product ID
+		for _, param := range c.Params {
+			modifiedPath = strings.Replace(modifiedPath, ":"+param.Key, param.Value, 1)
+		}
+
+		req.URL.Path = modifiedPath
+	}
+
+	// 5xx responses and connection errors count towards passive ejection
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			result = loadbalancer.Failed
 		}
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		// A client that disconnected says nothing about the upstream
+		if errors.Is(err, context.Canceled) || c.Request.Context().Err() != nil {
+			result = loadbalancer.Canceled
+			return
+		}
+		result = loadbalancer.Failed
+		log.Printf("Proxy error for %s instance %s: %v", service, instance, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, "upstream_timeout", service, "Upstream timed out")
+			return
+		}
+		writeError(w, http.StatusBadGateway, "upstream_unavailable", service, "Upstream unavailable")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.RequestTimeout())
+	defer cancel()
+
+	// Serve the request
+	proxy.ServeHTTP(w, c.Request.WithContext(ctx))
+	return result
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
 
-		// Serve the request
-		proxy.ServeHTTP(c.Writer, c.Request)
+// backoff returns an exponential delay with full jitter for the given retry.
+func backoff(cfg registry.RetryConfig, attempt int) time.Duration {
+	ceiling := cfg.BaseDelayDuration() << (attempt - 1)
+	if max := cfg.MaxDelayDuration(); ceiling > max || ceiling <= 0 {
+		ceiling = max
 	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// writeError writes the gateway's JSON error envelope. The "error" field
+// keeps the same shape as the services' own error responses.
+func writeError(w http.ResponseWriter, status int, code, service, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(gin.H{
+		"error":   message,
+		"code":    code,
+		"service": service,
+	})
+}
+
+// bufferedResponse holds an upstream response until we know whether the
+// attempt will be retried.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *bufferedResponse) Header() http.Header         { return r.header }
+func (r *bufferedResponse) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *bufferedResponse) WriteHeader(status int)      { r.status = status }
+
+func (r *bufferedResponse) copyTo(w http.ResponseWriter) {
+	for key, values := range r.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
 }
 
 // Helper function to get service URL
 func GetServiceURL(service string) (string, error) {
-	if url, exists := serviceURLs[service]; exists {
-		return url, nil
-	}
-	return "", fmt.Errorf("service %s not found", service)
+	return registry.GetServiceURL(service)
 }
@@ -0,0 +1,85 @@
+package loadbalancer
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Instance is a single upstream server inside a pool.
+type Instance struct {
+	URL *url.URL
+	raw string
+
+	active int64 // in-flight requests
+
+	mu                  sync.Mutex
+	healthy             bool
+	consecutiveFailures int
+	ejectedUntil        time.Time
+}
+
+func newInstance(raw string) (*Instance, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{URL: u, raw: raw, healthy: true}, nil
+}
+
+func (i *Instance) String() string {
+	return i.raw
+}
+
+// ActiveRequests returns the number of requests currently proxied to i.
+func (i *Instance) ActiveRequests() int64 {
+	return atomic.LoadInt64(&i.active)
+}
+
+// Available reports whether i passed its last health check and is not
+// currently ejected because of passive failures.
+func (i *Instance) Available() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.healthy && time.Now().After(i.ejectedUntil)
+}
+
+func (i *Instance) setHealthy(healthy bool) {
+	i.mu.Lock()
+	i.healthy = healthy
+	if healthy {
+		i.consecutiveFailures = 0
+	}
+	i.mu.Unlock()
+}
+
+// clearEjection reports whether i was ejected and the ejection has run out.
+// It returns true only once per ejection.
+func (i *Instance) clearEjection() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.ejectedUntil.IsZero() || time.Now().Before(i.ejectedUntil) {
+		return false
+	}
+	i.ejectedUntil = time.Time{}
+	return true
+}
+
+// recordResult updates the passive failure counter and reports whether the
+// instance was ejected by this call.
+func (i *Instance) recordResult(failed bool, threshold int, ejectFor time.Duration) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !failed {
+		i.consecutiveFailures = 0
+		return false
+	}
+	i.consecutiveFailures++
+	if i.consecutiveFailures < threshold {
+		return false
+	}
+	i.consecutiveFailures = 0
+	i.ejectedUntil = time.Now().Add(ejectFor)
+	return true
+}
@@ -0,0 +1,244 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RohithBN/gateway/registry"
+	"github.com/RohithBN/shared/metrics"
+)
+
+const (
+	defaultHealthPath   = "/health"
+	healthCheckInterval = 10 * time.Second
+	healthCheckTimeout  = 2 * time.Second
+	// failureThreshold consecutive 5xx/connection errors eject an instance
+	// for ejectionDuration.
+	failureThreshold = 3
+	ejectionDuration = 30 * time.Second
+)
+
+var ErrNoHealthyInstance = errors.New("no healthy upstream instance")
+
+// Pool is the set of instances serving one logical service.
+type Pool struct {
+	Service    string
+	instances  []*Instance
+	strategy   Strategy
+	healthPath string
+	cancel     context.CancelFunc
+}
+
+func newPool(service string, cfg registry.ServiceConfig, previous *Pool) *Pool {
+	healthPath := cfg.HealthPath
+	if healthPath == "" {
+		healthPath = defaultHealthPath
+	}
+	p := &Pool{
+		Service:    service,
+		strategy:   NewStrategy(cfg.Strategy),
+		healthPath: healthPath,
+	}
+
+	// Keep health and connection state for instances that survived a reload.
+	existing := map[string]*Instance{}
+	if previous != nil {
+		for _, inst := range previous.instances {
+			existing[inst.String()] = inst
+		}
+	}
+	for _, raw := range cfg.Instances {
+		if inst, ok := existing[raw]; ok {
+			p.instances = append(p.instances, inst)
+			delete(existing, raw)
+			continue
+		}
+		inst, err := newInstance(raw)
+		if err != nil {
+			log.Printf("Skipping invalid instance %s for %s: %v", raw, service, err)
+			continue
+		}
+		p.instances = append(p.instances, inst)
+	}
+	for raw := range existing {
+		metrics.UpstreamInstanceUp.DeleteLabelValues(service, raw)
+		metrics.UpstreamActiveRequests.DeleteLabelValues(service, raw)
+	}
+	p.exportState()
+	return p
+}
+
+// Instances returns every instance in the pool, available or not.
+func (p *Pool) Instances() []*Instance {
+	return append([]*Instance{}, p.instances...)
+}
+
+// Acquire selects an instance for a request and marks it busy. The caller
+// must call Release once the upstream response has been handled.
+func (p *Pool) Acquire(key string) (*Instance, error) {
+	var available []*Instance
+	for _, inst := range p.instances {
+		if !inst.Available() {
+			continue
+		}
+		if inst.clearEjection() {
+			log.Printf("Returning %s instance %s after ejection", p.Service, inst)
+			metrics.UpstreamInstanceUp.WithLabelValues(p.Service, inst.String()).Set(1)
+		}
+		available = append(available, inst)
+	}
+	inst := p.strategy.Next(available, key)
+	if inst == nil {
+		return nil, ErrNoHealthyInstance
+	}
+	n := atomic.AddInt64(&inst.active, 1)
+	metrics.UpstreamActiveRequests.WithLabelValues(p.Service, inst.String()).Set(float64(n))
+	return inst, nil
+}
+
+// Result is the outcome of a request proxied to an instance.
+type Result int
+
+const (
+	Succeeded Result = iota
+	// Failed is a connection error, timeout or 5xx response.
+	Failed
+	// Canceled requests were abandoned by the client and say nothing about
+	// the instance.
+	Canceled
+)
+
+// Release returns inst to the pool. Failed results count towards ejecting
+// misbehaving instances.
+func (p *Pool) Release(inst *Instance, result Result) {
+	n := atomic.AddInt64(&inst.active, -1)
+	metrics.UpstreamActiveRequests.WithLabelValues(p.Service, inst.String()).Set(float64(n))
+	if result == Canceled {
+		return
+	}
+	if inst.recordResult(result == Failed, failureThreshold, ejectionDuration) {
+		log.Printf("Ejecting %s instance %s for %s", p.Service, inst, ejectionDuration)
+		metrics.UpstreamEjections.WithLabelValues(p.Service, inst.String()).Inc()
+		metrics.UpstreamInstanceUp.WithLabelValues(p.Service, inst.String()).Set(0)
+	}
+}
+
+func (p *Pool) runHealthChecks(ctx context.Context) {
+	client := &http.Client{Timeout: healthCheckTimeout}
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.checkAll(ctx, client)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) checkAll(ctx context.Context, client *http.Client) {
+	for _, inst := range p.instances {
+		healthy := p.check(ctx, client, inst)
+		if healthy != inst.Available() {
+			log.Printf("Upstream %s instance %s healthy=%v", p.Service, inst, healthy)
+		}
+		inst.setHealthy(healthy)
+	}
+	p.exportState()
+}
+
+func (p *Pool) check(ctx context.Context, client *http.Client, inst *Instance) bool {
+	target := strings.TrimRight(inst.String(), "/") + p.healthPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func (p *Pool) exportState() {
+	for _, inst := range p.instances {
+		inst.clearEjection()
+		up := 0.0
+		if inst.Available() {
+			up = 1
+		}
+		metrics.UpstreamInstanceUp.WithLabelValues(p.Service, inst.String()).Set(up)
+	}
+}
+
+// Manager owns one pool per registered service and rebuilds them when the
+// registry reloads.
+type Manager struct {
+	mu    sync.RWMutex
+	ctx   context.Context
+	pools map[string]*Pool
+}
+
+// Pools is the manager used by the proxy handlers.
+var Pools = &Manager{pools: map[string]*Pool{}}
+
+// Start builds pools from reg, starts their health checks and keeps them in
+// sync with registry reloads until ctx is canceled.
+func (m *Manager) Start(ctx context.Context, reg *registry.Registry) {
+	m.mu.Lock()
+	m.ctx = ctx
+	m.mu.Unlock()
+
+	m.update(reg.Services())
+	reg.OnReload(m.update)
+}
+
+// Get returns the pool for service.
+func (m *Manager) Get(service string) (*Pool, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.pools[service]
+	return p, ok
+}
+
+func (m *Manager) update(services map[string]registry.ServiceConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pools := make(map[string]*Pool, len(services))
+	for name, cfg := range services {
+		old := m.pools[name]
+		p := newPool(name, cfg, old)
+		if old != nil {
+			old.cancel()
+		}
+		checkCtx, cancel := context.WithCancel(ctx)
+		p.cancel = cancel
+		go p.runHealthChecks(checkCtx)
+		pools[name] = p
+	}
+	for name, old := range m.pools {
+		if _, ok := pools[name]; !ok {
+			old.cancel()
+			for _, inst := range old.instances {
+				metrics.UpstreamInstanceUp.DeleteLabelValues(name, inst.String())
+				metrics.UpstreamActiveRequests.DeleteLabelValues(name, inst.String())
+			}
+		}
+	}
+	m.pools = pools
+}
@@ -0,0 +1,134 @@
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+	ConsistentHash   = "consistent_hash"
+)
+
+// Strategy picks one of the available instances for a request. key is the
+// value used for sticky routing (the caller's X-User-ID) and may be empty.
+type Strategy interface {
+	Next(instances []*Instance, key string) *Instance
+}
+
+// NewStrategy returns the strategy registered under name, falling back to
+// round-robin for unknown or empty names.
+func NewStrategy(name string) Strategy {
+	switch name {
+	case LeastConnections:
+		return &leastConnections{}
+	case ConsistentHash:
+		return &consistentHash{}
+	default:
+		return &roundRobin{}
+	}
+}
+
+type roundRobin struct {
+	counter uint64
+}
+
+func (r *roundRobin) Next(instances []*Instance, key string) *Instance {
+	if len(instances) == 0 {
+		return nil
+	}
+	n := atomic.AddUint64(&r.counter, 1)
+	return instances[(n-1)%uint64(len(instances))]
+}
+
+type leastConnections struct{}
+
+func (leastConnections) Next(instances []*Instance, key string) *Instance {
+	var best *Instance
+	for _, inst := range instances {
+		if best == nil || inst.ActiveRequests() < best.ActiveRequests() {
+			best = inst
+		}
+	}
+	return best
+}
+
+// virtualNodes is the number of points each instance gets on the hash ring.
+const virtualNodes = 100
+
+type consistentHash struct {
+	fallback roundRobin
+	ring     atomic.Pointer[hashRing]
+}
+
+type ringPoint struct {
+	hash uint32
+	inst *Instance
+}
+
+// hashRing places virtualNodes points per instance. It is built from the
+// currently available instances so that a key only moves when the instance
+// it was pinned to goes away.
+type hashRing struct {
+	instances []*Instance
+	points    []ringPoint
+}
+
+func newHashRing(instances []*Instance) *hashRing {
+	r := &hashRing{
+		instances: instances,
+		points:    make([]ringPoint, 0, len(instances)*virtualNodes),
+	}
+	for _, inst := range instances {
+		for v := 0; v < virtualNodes; v++ {
+			r.points = append(r.points, ringPoint{hashKey(inst.String() + "#" + strconv.Itoa(v)), inst})
+		}
+	}
+	sort.Slice(r.points, func(a, b int) bool { return r.points[a].hash < r.points[b].hash })
+	return r
+}
+
+// builtFrom reports whether r was built from exactly instances.
+func (r *hashRing) builtFrom(instances []*Instance) bool {
+	if len(r.instances) != len(instances) {
+		return false
+	}
+	for i, inst := range instances {
+		if r.instances[i] != inst {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *consistentHash) Next(instances []*Instance, key string) *Instance {
+	if key == "" {
+		return h.fallback.Next(instances, key)
+	}
+	if len(instances) == 0 {
+		return nil
+	}
+
+	// The ring is only rebuilt when the available instances change.
+	ring := h.ring.Load()
+	if ring == nil || !ring.builtFrom(instances) {
+		ring = newHashRing(instances)
+		h.ring.Store(ring)
+	}
+
+	target := hashKey(key)
+	idx := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= target })
+	if idx == len(ring.points) {
+		idx = 0
+	}
+	return ring.points[idx].inst
+}
+
+func hashKey(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
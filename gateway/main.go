@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"time"
 
+	"github.com/RohithBN/gateway/circuitbreaker"
 	"github.com/RohithBN/gateway/handlers"
+	"github.com/RohithBN/gateway/loadbalancer"
 	"github.com/RohithBN/gateway/middleware"
+	"github.com/RohithBN/gateway/registry"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/metrics"
+	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -16,19 +25,81 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
-	router := gin.Default()
+	logging.Init("gateway")
+
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway")
+	if err != nil {
+		log.Fatalf("Error initializing tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load upstream services from GATEWAY_REGISTRY_FILE (defaults to localhost)
+	if err := registry.Init(os.Getenv("GATEWAY_REGISTRY_FILE")); err != nil {
+		log.Fatalf("Error loading service registry: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Default.Watch(ctx, 5*time.Second)
+
+	// Build instance pools and start active health checks
+	loadbalancer.Pools.Start(ctx, registry.Default)
+	circuitbreaker.Configure(registry.Default)
+
+	// Redis holds the token revocation list and the shared rate limit buckets
+	redisErr := redis.ConnectRedis()
+	if redisErr != nil {
+		log.Printf("Error connecting to Redis: %v", redisErr)
+	}
+
+	// RATE_LIMIT_BACKEND selects redis (default) or memory
+	middleware.InitRateLimiter(redisErr == nil)
+	if err := middleware.LoadRateLimitPolicies(os.Getenv("RATE_LIMIT_POLICIES_FILE")); err != nil {
+		log.Fatalf("Error loading rate limit policies: %v", err)
+	}
+
+	router := logging.NewRouter("gateway")
 	router.Use(metrics.PrometheusMiddleware())
 
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	// Public routes
-	router.POST("/api/register", handlers.ProxyHandler("auth", "/register"))
-	router.POST("/api/login", handlers.ProxyHandler("auth", "/login"))
+	// Public routes, rate limited by client IP
+	public := router.Group("/api")
+	public.Use(middleware.RateLimitMiddleware())
+	{
+		public.POST("/register", handlers.ProxyHandler("auth", "/register"))
+		public.POST("/login", handlers.ProxyHandler("auth", "/login"))
+		public.POST("/refresh", handlers.ProxyHandler("auth", "/refresh"))
+		public.GET("/verify-email", handlers.ProxyHandler("auth", "/verify-email"))
+		public.POST("/verify-email/resend", handlers.ProxyHandler("auth", "/verify-email/resend"))
+		public.POST("/password/forgot", handlers.ProxyHandler("auth", "/password/forgot"))
+		public.POST("/password/reset", handlers.ProxyHandler("auth", "/password/reset"))
+	}
+	router.GET("/.well-known/jwks.json", handlers.ProxyHandler("auth", "/.well-known/jwks.json"))
 
 	// Protected routes
 	api := router.Group("/api")
 	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthorizeMiddleware())
 	api.Use(middleware.RateLimitMiddleware())
 	{
+		// Auth
+		api.POST("/logout", handlers.ProxyHandler("auth", "/logout"))
+		api.POST("/mfa/totp/enroll", handlers.ProxyHandler("auth", "/mfa/totp/enroll"))
+		api.POST("/mfa/totp/confirm", handlers.ProxyHandler("auth", "/mfa/totp/confirm"))
+		api.POST("/mfa/totp/disable", handlers.ProxyHandler("auth", "/mfa/totp/disable"))
+		api.POST("/mfa/totp/verify", handlers.ProxyHandler("auth", "/mfa/totp/verify"))
+
+		// Profile
+		api.GET("/me", handlers.ProxyHandler("auth", "/me"))
+		api.PATCH("/me", handlers.ProxyHandler("auth", "/me"))
+		api.POST("/me/password", handlers.ProxyHandler("auth", "/me/password"))
+		api.GET("/me/addresses", handlers.ProxyHandler("auth", "/me/addresses"))
+		api.POST("/me/addresses", handlers.ProxyHandler("auth", "/me/addresses"))
+		api.GET("/me/addresses/:id", handlers.ProxyHandler("auth", "/me/addresses/:id"))
+		api.PUT("/me/addresses/:id", handlers.ProxyHandler("auth", "/me/addresses/:id"))
+		api.DELETE("/me/addresses/:id", handlers.ProxyHandler("auth", "/me/addresses/:id"))
+		api.POST("/me/addresses/:id/default", handlers.ProxyHandler("auth", "/me/addresses/:id/default"))
+
 		// Products
 		api.GET("/products", handlers.ProxyHandler("products", "/products"))
 		api.POST("/add-product", handlers.ProxyHandler("products", "/add-product"))
@@ -47,6 +118,9 @@ func main() {
 		api.POST("/orders/payment", handlers.ProxyHandler("orders", "/orders/payment"))
 		api.PUT("/orders/:orderId/status", handlers.ProxyHandler("orders", "/orders/:orderId/status"))
 		api.GET("/orders", handlers.ProxyHandler("orders", "/orders"))
+
+		// Admin
+		api.GET("/admin/rate-limits", middleware.RateLimitPoliciesHandler)
 	}
 
 	log.Printf("API Gateway starting on port 8080")
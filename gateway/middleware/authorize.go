@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+)
+
+type Permission string
+
+const (
+	PermProductsRead  Permission = "products:read"
+	PermProductsWrite Permission = "products:write"
+	PermCartManage    Permission = "cart:manage"
+	PermOrdersOwn     Permission = "orders:own"
+	PermOrdersFulfill Permission = "orders:fulfill"
+	PermSessionManage Permission = "session:manage"
+	PermProfileManage Permission = "profile:manage"
+	PermAdminRead     Permission = "admin:read"
+)
+
+// rolePermissions lists what each role may do. Customers can browse the
+// catalog and manage their own cart and orders; staff additionally run the
+// catalog and fulfillment; admins can also read gateway admin endpoints.
+var rolePermissions = map[string][]Permission{
+	types.RoleCustomer: {PermProductsRead, PermCartManage, PermOrdersOwn, PermSessionManage, PermProfileManage},
+	types.RoleStaff:    {PermProductsRead, PermProductsWrite, PermCartManage, PermOrdersOwn, PermOrdersFulfill, PermSessionManage, PermProfileManage},
+	types.RoleAdmin:    {PermProductsRead, PermProductsWrite, PermCartManage, PermOrdersOwn, PermOrdersFulfill, PermSessionManage, PermProfileManage, PermAdminRead},
+}
+
+// routePermissions maps "METHOD route-template" to the permission it needs.
+// Protected routes missing from this table are denied.
+var routePermissions = map[string]Permission{
+	"POST /api/logout":           PermSessionManage,
+	"POST /api/mfa/totp/enroll":  PermSessionManage,
+	"POST /api/mfa/totp/confirm": PermSessionManage,
+	"POST /api/mfa/totp/disable": PermSessionManage,
+	"POST /api/mfa/totp/verify":  PermSessionManage,
+
+	"GET /api/me":                        PermProfileManage,
+	"PATCH /api/me":                      PermProfileManage,
+	"POST /api/me/password":              PermProfileManage,
+	"GET /api/me/addresses":              PermProfileManage,
+	"POST /api/me/addresses":             PermProfileManage,
+	"GET /api/me/addresses/:id":          PermProfileManage,
+	"PUT /api/me/addresses/:id":          PermProfileManage,
+	"DELETE /api/me/addresses/:id":       PermProfileManage,
+	"POST /api/me/addresses/:id/default": PermProfileManage,
+
+	"GET /api/products":              PermProductsRead,
+	"GET /api/products/:id":          PermProductsRead,
+	"POST /api/add-product":          PermProductsWrite,
+	"PUT /api/update-product/:id":    PermProductsWrite,
+	"DELETE /api/delete-product/:id": PermProductsWrite,
+
+	"POST /api/cart/:productId":   PermCartManage,
+	"GET /api/cart":               PermCartManage,
+	"DELETE /api/cart/:productId": PermCartManage,
+
+	"POST /api/create-order":          PermOrdersOwn,
+	"POST /api/orders/send-otp":       PermOrdersOwn,
+	"POST /api/orders/verify-otp":     PermOrdersOwn,
+	"POST /api/orders/payment":        PermOrdersOwn,
+	"GET /api/orders":                 PermOrdersOwn,
+	"PUT /api/orders/:orderId/status": PermOrdersFulfill,
+
+	"GET /api/admin/rate-limits": PermAdminRead,
+}
+
+// Roles returns the caller's roles from the token claims. Tokens issued
+// before roles were introduced are treated as customers.
+func Roles(c *gin.Context) []string {
+	claims := Claims(c)
+	if claims == nil {
+		return nil
+	}
+	raw, ok := claims["roles"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return []string{types.RoleCustomer}
+	}
+	roles := make([]string, 0, len(raw))
+	for _, r := range raw {
+		if s, ok := r.(string); ok && s != "" {
+			roles = append(roles, s)
+		}
+	}
+	return roles
+}
+
+// HasPermission reports whether any of roles grants perm.
+func HasPermission(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AuthorizeMiddleware checks the route-to-permission table. It must run after
+// AuthMiddleware.
+func AuthorizeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perm, ok := routePermissions[fmt.Sprintf("%s %s", c.Request.Method, c.FullPath())]
+		if !ok {
+			c.JSON(403, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+		if !HasPermission(Roles(c), perm) {
+			c.JSON(403, gin.H{"error": "Forbidden", "required_permission": perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func joinRoles(roles []string) string {
+	return strings.Join(roles, ",")
+}
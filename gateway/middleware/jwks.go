@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RohithBN/gateway/registry"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	jwksCacheTTL = 5 * time.Minute
+	// jwksMinRefresh limits refetches triggered by unknown kids so garbage
+	// tokens cannot hammer the auth service.
+	jwksMinRefresh = 30 * time.Second
+)
+
+// jwksCache fetches the auth service's JWKS and caches the parsed keys. An
+// unknown kid triggers a refetch so newly rotated keys are picked up without
+// waiting for the cache to expire.
+type jwksCache struct {
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	client    *http.Client
+}
+
+var jwks = &jwksCache{client: &http.Client{Timeout: 5 * time.Second}}
+
+func (j *jwksCache) key(ctx context.Context, kid string) (interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > jwksCacheTTL
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(j.fetchedAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		// Keep serving the last known keys if the auth service is briefly down
+		if ok {
+			log.Printf("Error refreshing JWKS, using cached keys: %v", err)
+			return key, nil
+		}
+		return nil, err
+	}
+	j.keys = keys
+	j.fetchedAt = time.Now()
+
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (j *jwksCache) fetch(ctx context.Context) (map[string]interface{}, error) {
+	url := os.Getenv("JWKS_URL")
+	if url == "" {
+		authURL, err := registry.GetServiceURL("auth")
+		if err != nil {
+			return nil, err
+		}
+		url = authURL + "/.well-known/jwks.json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, errN := decodeBigInt(k.N)
+			e, errE := decodeBigInt(k.E)
+			if errN != nil || errE != nil {
+				log.Printf("Skipping malformed RSA key %s in JWKS", k.Kid)
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			x, errX := decodeBigInt(k.X)
+			y, errY := decodeBigInt(k.Y)
+			if k.Crv != "P-256" || errX != nil || errY != nil {
+				log.Printf("Skipping unsupported EC key %s in JWKS", k.Kid)
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+	return keys, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// verificationKey is the jwt.Keyfunc used by AuthMiddleware. Only RS256/ES256
+// tokens whose kid is in the JWKS are accepted, unless JWT_SIGNING_ALG=HS256
+// opts into HMAC tokens signed with JWT_SECRET_KEY for local development.
+// The key type always has to match the token's algorithm.
+func verificationKey(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		alg := strings.ToUpper(os.Getenv("JWT_SIGNING_ALG"))
+		if alg == "HS256" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			secretKey := os.Getenv("JWT_SECRET_KEY")
+			if secretKey == "" {
+				return nil, fmt.Errorf("JWT_SECRET_KEY not found in environment")
+			}
+			return []byte(secretKey), nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no kid")
+		}
+		key, err := jwks.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA:
+			if _, ok := key.(*rsa.PublicKey); ok {
+				return key, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); ok {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RohithBN/shared/redis"
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter decides whether the caller identified by key may make another
+// request under the given bucket size and refill rate.
+type Limiter interface {
+	Allow(ctx context.Context, key string, bucketSize, refillRate float64) (RateLimitResult, error)
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  float64
+	RetryAfter time.Duration
+}
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+var (
+	memory                 = newMemoryLimiter()
+	limiter        Limiter = memory
+	limiterBackend         = BackendMemory
+)
+
+// InitRateLimiter selects the limiter backend from RATE_LIMIT_BACKEND
+// ("redis" or "memory"). redisAvailable reports whether the gateway could
+// connect to Redis at startup; if not, the in-memory limiter is used.
+func InitRateLimiter(redisAvailable bool) {
+	backend := os.Getenv("RATE_LIMIT_BACKEND")
+	if backend == "" {
+		backend = BackendRedis
+	}
+
+	switch backend {
+	case BackendRedis:
+		if !redisAvailable {
+			log.Printf("Redis unavailable, using in-memory rate limiter")
+			limiter, limiterBackend = memory, BackendMemory
+			return
+		}
+		limiter, limiterBackend = &redisLimiter{fallback: memory}, BackendRedis
+	default:
+		limiter, limiterBackend = memory, BackendMemory
+	}
+	log.Printf("Rate limiter backend: %s", limiterBackend)
+}
+
+// RateLimitMiddleware applies the first matching RateLimitPolicy. It can be
+// used on public routes too, where callers are keyed by client IP.
+func RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := matchPolicy(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), policy.key(c), policy.bucketSize(), policy.refillRate())
+		if err != nil {
+			// Never block traffic because the limiter itself is broken
+			log.Printf("Rate limiter error: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(int(math.Floor(result.Remaining))))
+
+		if result.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(429, gin.H{
+			"error":       "Rate limit exceeded",
+			"retry_after": retryAfter,
+		})
+		c.Abort()
+	}
+}
+
+// redisLimiter keeps buckets in Redis so limits are shared by every gateway
+// replica and survive restarts.
+type redisLimiter struct {
+	fallback Limiter
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, key string, bucketSize, refillRate float64) (RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	allowed, remaining, err := redis.TakeToken(ctx, "ratelimit:"+key, bucketSize, refillRate, 1)
+	if err != nil {
+		log.Printf("Redis rate limiter error, using in-memory fallback: %v", err)
+		return r.fallback.Allow(ctx, key, bucketSize, refillRate)
+	}
+	return RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  remaining,
+		RetryAfter: redis.RetryAfter(remaining, refillRate),
+	}, nil
+}
+
+type ClientData struct {
+	tokensRemaining float64
+	lastRefillTime  int64
+	mu              sync.Mutex // Per-client mutex for better concurrency
+}
+
+// memoryLimiter is the original per-process token bucket.
+type memoryLimiter struct {
+	clients map[string]*ClientData
+	mu      sync.RWMutex
+}
+
+var cleanupInterval = 10 * time.Minute
+
+func newMemoryLimiter() *memoryLimiter {
+	m := &memoryLimiter{clients: make(map[string]*ClientData)}
+	// Cleanup routine for inactive users
+	go func() {
+		for {
+			time.Sleep(cleanupInterval)
+			m.cleanupInactiveClients()
+		}
+	}()
+	return m
+}
+
+func (m *memoryLimiter) cleanupInactiveClients() {
+	//represents the last 30 min from current time
+	threshold := time.Now().Add(-30*time.Minute).UnixNano() / 1e6
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, client := range m.clients {
+		client.mu.Lock()
+		//if users last req is older than threshold , delete the user from map
+		if client.lastRefillTime < threshold {
+			delete(m.clients, key)
+		}
+		client.mu.Unlock()
+	}
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, key string, bucketSize, refillRate float64) (RateLimitResult, error) {
+	m.mu.RLock()
+	//check if client exists
+	client, exists := m.clients[key]
+	m.mu.RUnlock()
+
+	if !exists {
+		m.mu.Lock()
+		client, exists = m.clients[key]
+		if !exists {
+			// if client doesnt exist then create a new client , with current time as last req
+			client = &ClientData{
+				tokensRemaining: bucketSize,
+				lastRefillTime:  time.Now().UnixNano() / 1e6,
+			}
+			m.clients[key] = client
+		}
+		m.mu.Unlock()
+	}
+
+	// Lock this client's data for the duration of our check/update
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// Calculate token refill based on time elapsed
+	currentTime := time.Now().UnixNano() / 1e6
+	//how many sec diff btwn now nad last req
+	elapsedSeconds := float64(currentTime-client.lastRefillTime) / 1000.0
+	//twlls how many tokens to add based on refillrate
+	tokensToAdd := elapsedSeconds * refillRate
+
+	if tokensToAdd > 0 {
+		//if there are tokens to add , add it to remaining tokens , but ensure using min() that it doesnt exceed the max bucket size
+		client.tokensRemaining = math.Min(bucketSize, client.tokensRemaining+tokensToAdd)
+		client.lastRefillTime = currentTime // last req=now
+	}
+
+	if client.tokensRemaining >= 1.0 {
+		// Consume one token
+		client.tokensRemaining -= 1.0
+		return RateLimitResult{Allowed: true, Remaining: client.tokensRemaining}, nil
+	}
+	return RateLimitResult{
+		Remaining:  client.tokensRemaining,
+		RetryAfter: time.Duration((1.0 - client.tokensRemaining) / refillRate * float64(time.Second)),
+	}, nil
+}
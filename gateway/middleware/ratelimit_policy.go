@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	KeyByUser = "user"
+	KeyByIP   = "ip"
+)
+
+// RateLimitPolicy is a declarative limit. A request is matched against the
+// policies in order and the first match decides its bucket.
+type RateLimitPolicy struct {
+	Name string `json:"name" yaml:"name"`
+	// Route is a gin route template such as /api/orders/send-otp. A trailing
+	// "*" matches by prefix and an empty route matches everything.
+	Route   string   `json:"route" yaml:"route"`
+	Methods []string `json:"methods,omitempty" yaml:"methods"`
+	// Claim and Values restrict the policy to tokens whose claim has one of
+	// the given values, e.g. claim: roles, values: [admin].
+	Claim  string   `json:"claim,omitempty" yaml:"claim"`
+	Values []string `json:"values,omitempty" yaml:"values"`
+	// Key is "user" (the authenticated X-User-ID, falling back to the client
+	// IP for anonymous requests) or "ip".
+	Key    string `json:"key" yaml:"key"`
+	Limit  int    `json:"limit" yaml:"limit"`
+	Period string `json:"period" yaml:"period"`
+
+	period time.Duration
+}
+
+type rateLimitConfig struct {
+	Policies []RateLimitPolicy `json:"policies" yaml:"policies"`
+}
+
+// defaultPolicies apply when RATE_LIMIT_POLICIES_FILE is not set.
+var defaultPolicies = []RateLimitPolicy{
+	{Name: "auth-anonymous", Route: "/api/register", Methods: []string{"POST"}, Key: KeyByIP, Limit: 5, Period: "1m"},
+	{Name: "login-anonymous", Route: "/api/login", Methods: []string{"POST"}, Key: KeyByIP, Limit: 10, Period: "1m"},
+	{Name: "verify-email", Route: "/api/verify-email*", Key: KeyByIP, Limit: 10, Period: "1m"},
+	{Name: "password-reset", Route: "/api/password/*", Methods: []string{"POST"}, Key: KeyByIP, Limit: 5, Period: "1m"},
+	{Name: "mfa", Route: "/api/mfa/*", Methods: []string{"POST"}, Key: KeyByUser, Limit: 10, Period: "1m"},
+	{Name: "send-otp", Route: "/api/orders/send-otp", Methods: []string{"POST"}, Key: KeyByUser, Limit: 3, Period: "10m"},
+	{Name: "browse-products", Route: "/api/products*", Methods: []string{"GET"}, Key: KeyByUser, Limit: 60, Period: "1m"},
+	{Name: "default", Key: KeyByUser, Limit: 5, Period: "1m"},
+}
+
+var (
+	policies   []RateLimitPolicy
+	policiesMu sync.RWMutex
+)
+
+func init() {
+	if err := setPolicies(defaultPolicies); err != nil {
+		panic(err)
+	}
+}
+
+// LoadRateLimitPolicies replaces the active policies with the ones in path
+// (YAML or JSON). An empty path keeps the built-in defaults.
+func LoadRateLimitPolicies(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rate limit policies: %v", err)
+	}
+
+	var cfg rateLimitConfig
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse rate limit policies: %v", err)
+	}
+	return setPolicies(cfg.Policies)
+}
+
+func setPolicies(list []RateLimitPolicy) error {
+	parsed := make([]RateLimitPolicy, 0, len(list))
+	for i, p := range list {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("policy-%d", i)
+		}
+		if p.Key == "" {
+			p.Key = KeyByUser
+		}
+		if p.Key != KeyByUser && p.Key != KeyByIP {
+			return fmt.Errorf("policy %s: unknown key %q", p.Name, p.Key)
+		}
+		if p.Limit <= 0 {
+			return fmt.Errorf("policy %s: limit must be positive", p.Name)
+		}
+		period, err := time.ParseDuration(p.Period)
+		if err != nil || period <= 0 {
+			return fmt.Errorf("policy %s: invalid period %q", p.Name, p.Period)
+		}
+		p.period = period
+		parsed = append(parsed, p)
+	}
+
+	policiesMu.Lock()
+	policies = parsed
+	policiesMu.Unlock()
+	return nil
+}
+
+// RateLimitPolicies returns the active policies in match order.
+func RateLimitPolicies() []RateLimitPolicy {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	return append([]RateLimitPolicy{}, policies...)
+}
+
+// RateLimitPoliciesHandler exposes the active policies to operators.
+func RateLimitPoliciesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"backend":  limiterBackend,
+		"policies": RateLimitPolicies(),
+	})
+}
+
+func matchPolicy(c *gin.Context) (RateLimitPolicy, bool) {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	for _, p := range policies {
+		if p.matches(c) {
+			return p, true
+		}
+	}
+	return RateLimitPolicy{}, false
+}
+
+func (p RateLimitPolicy) matches(c *gin.Context) bool {
+	route := c.FullPath()
+	switch {
+	case p.Route == "" || p.Route == "*":
+	case strings.HasSuffix(p.Route, "*"):
+		if !strings.HasPrefix(route, strings.TrimSuffix(p.Route, "*")) {
+			return false
+		}
+	case p.Route != route:
+		return false
+	}
+
+	if len(p.Methods) > 0 && !containsFold(p.Methods, c.Request.Method) {
+		return false
+	}
+
+	if p.Claim != "" {
+		claims := Claims(c)
+		if claims == nil || !claimMatches(claims[p.Claim], p.Values) {
+			return false
+		}
+	}
+	return true
+}
+
+// bucketSize and refillRate translate "limit per period" into the token
+// bucket parameters used by the limiters.
+func (p RateLimitPolicy) bucketSize() float64 {
+	return float64(p.Limit)
+}
+
+func (p RateLimitPolicy) refillRate() float64 {
+	return float64(p.Limit) / p.period.Seconds()
+}
+
+func (p RateLimitPolicy) key(c *gin.Context) string {
+	// Only trust X-User-ID once AuthMiddleware has set it from a token
+	if p.Key == KeyByUser && Claims(c) != nil {
+		if userId := c.GetHeader("X-User-ID"); userId != "" {
+			return p.Name + ":user:" + userId
+		}
+	}
+	return p.Name + ":ip:" + c.ClientIP()
+}
+
+func claimMatches(value interface{}, allowed []string) bool {
+	switch v := value.(type) {
+	case string:
+		return containsFold(allowed, v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && containsFold(allowed, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,351 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServiceConfig describes one logical upstream service and its instances.
+type ServiceConfig struct {
+	Instances []string `json:"instances" yaml:"instances"`
+	// Strategy is round_robin (default), least_connections or consistent_hash.
+	Strategy   string `json:"strategy" yaml:"strategy"`
+	HealthPath string `json:"health_path" yaml:"health_path"`
+	// Timeout bounds a single upstream attempt, e.g. "10s".
+	Timeout        string               `json:"timeout" yaml:"timeout"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker" yaml:"circuit_breaker"`
+	Retry          RetryConfig          `json:"retry" yaml:"retry"`
+}
+
+// CircuitBreakerConfig controls when the gateway stops sending traffic to a
+// failing service and how it probes for recovery.
+type CircuitBreakerConfig struct {
+	FailureThreshold int    `json:"failure_threshold" yaml:"failure_threshold"`
+	CoolDown         string `json:"cool_down" yaml:"cool_down"`
+	HalfOpenRequests int    `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// RetryConfig controls retries of idempotent requests (GET, PUT, DELETE).
+type RetryConfig struct {
+	MaxAttempts int    `json:"max_attempts" yaml:"max_attempts"`
+	BaseDelay   string `json:"base_delay" yaml:"base_delay"`
+	MaxDelay    string `json:"max_delay" yaml:"max_delay"`
+}
+
+const (
+	defaultTimeout          = 10 * time.Second
+	defaultFailureThreshold = 5
+	defaultCoolDown         = 30 * time.Second
+	defaultHalfOpenRequests = 1
+	defaultMaxAttempts      = 3
+	defaultBaseDelay        = 100 * time.Millisecond
+	defaultMaxDelay         = 2 * time.Second
+)
+
+// RequestTimeout returns the per-attempt upstream timeout.
+func (s ServiceConfig) RequestTimeout() time.Duration {
+	return durationOr(s.Timeout, defaultTimeout)
+}
+
+func (c CircuitBreakerConfig) Threshold() int {
+	return intOr(c.FailureThreshold, defaultFailureThreshold)
+}
+
+func (c CircuitBreakerConfig) CoolDownDuration() time.Duration {
+	return durationOr(c.CoolDown, defaultCoolDown)
+}
+
+func (c CircuitBreakerConfig) HalfOpenLimit() int {
+	return intOr(c.HalfOpenRequests, defaultHalfOpenRequests)
+}
+
+// Attempts returns the total number of tries, including the first one.
+func (c RetryConfig) Attempts() int {
+	return intOr(c.MaxAttempts, defaultMaxAttempts)
+}
+
+func (c RetryConfig) BaseDelayDuration() time.Duration {
+	return durationOr(c.BaseDelay, defaultBaseDelay)
+}
+
+func (c RetryConfig) MaxDelayDuration() time.Duration {
+	return durationOr(c.MaxDelay, defaultMaxDelay)
+}
+
+func (s ServiceConfig) validate() error {
+	if len(s.Instances) == 0 {
+		return fmt.Errorf("no instances")
+	}
+	for _, d := range []string{s.Timeout, s.CircuitBreaker.CoolDown, s.Retry.BaseDelay, s.Retry.MaxDelay} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("invalid duration %q", d)
+		}
+	}
+	return nil
+}
+
+func durationOr(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+func intOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// Config is the on-disk layout of the registry file (YAML or JSON).
+type Config struct {
+	Services map[string]ServiceConfig `json:"services" yaml:"services"`
+}
+
+// defaultServices keeps the gateway usable for local development when no
+// registry file is configured.
+var defaultServices = map[string]ServiceConfig{
+	"auth":     {Instances: []string{"http://localhost:8081"}},
+	"products": {Instances: []string{"http://localhost:8082"}},
+	"cart":     {Instances: []string{"http://localhost:8083"}},
+	"orders":   {Instances: []string{"http://localhost:8084"}},
+}
+
+// envPrefix is used for per-service overrides, e.g.
+// GATEWAY_SERVICE_ORDERS=http://orders-1:8084,http://orders-2:8084
+const envPrefix = "GATEWAY_SERVICE_"
+
+type Registry struct {
+	mu        sync.RWMutex
+	path      string
+	modTime   time.Time
+	services  map[string]ServiceConfig
+	listeners []func(map[string]ServiceConfig)
+}
+
+// Default is the registry used by the proxy handlers.
+var Default = New("")
+
+// New creates a registry backed by the file at path. An empty path means
+// only the built-in defaults and environment overrides are used.
+func New(path string) *Registry {
+	r := &Registry{path: path}
+	r.services = applyEnvOverrides(copyServices(defaultServices))
+	return r
+}
+
+// Init points the default registry at path and loads it.
+func Init(path string) error {
+	Default = New(path)
+	return Default.Reload()
+}
+
+// Reload re-reads the registry file and environment overrides and swaps the
+// service table atomically. Requests that already resolved an instance keep
+// using it, so in-flight traffic is not interrupted.
+func (r *Registry) Reload() error {
+	services := copyServices(defaultServices)
+	var modTime time.Time
+
+	if r.path != "" {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			return fmt.Errorf("failed to stat registry file: %v", err)
+		}
+		modTime = info.ModTime()
+
+		cfg, err := loadFile(r.path)
+		if err != nil {
+			return err
+		}
+		for name, svc := range cfg.Services {
+			services[name] = svc
+		}
+	}
+	services = applyEnvOverrides(services)
+
+	for name, svc := range services {
+		if err := svc.validate(); err != nil {
+			return fmt.Errorf("service %s: %v", name, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.services = services
+	r.modTime = modTime
+	listeners := append([]func(map[string]ServiceConfig){}, r.listeners...)
+	r.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(copyServices(services))
+	}
+	log.Printf("Service registry loaded: %s", strings.Join(r.Names(), ", "))
+	return nil
+}
+
+// OnReload registers fn to be called with the new service table after every
+// successful reload.
+func (r *Registry) OnReload(fn func(map[string]ServiceConfig)) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, fn)
+	r.mu.Unlock()
+}
+
+// Instances returns the upstream base URLs configured for service.
+func (r *Registry) Instances(service string) ([]string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	svc, ok := r.services[service]
+	if !ok {
+		return nil, false
+	}
+	return append([]string{}, svc.Instances...), true
+}
+
+// Service returns the configuration of a single service.
+func (r *Registry) Service(service string) (ServiceConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	svc, ok := r.services[service]
+	if !ok {
+		return ServiceConfig{}, false
+	}
+	svc.Instances = append([]string{}, svc.Instances...)
+	return svc, true
+}
+
+// Services returns a copy of the whole service table.
+func (r *Registry) Services() map[string]ServiceConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return copyServices(r.services)
+}
+
+// Names returns the configured service names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.services))
+	for name := range r.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Watch reloads the registry on SIGHUP and whenever the file's modification
+// time changes. It blocks until ctx is canceled.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading service registry")
+			if err := r.Reload(); err != nil {
+				log.Printf("Error reloading service registry: %v", err)
+			}
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				log.Printf("Error reloading service registry: %v", err)
+			}
+		}
+	}
+}
+
+func (r *Registry) changed() bool {
+	if r.path == "" {
+		return false
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !info.ModTime().Equal(r.modTime)
+}
+
+// GetServiceURL returns the first configured instance of service from the
+// default registry.
+func GetServiceURL(service string) (string, error) {
+	instances, ok := Default.Instances(service)
+	if !ok || len(instances) == 0 {
+		return "", fmt.Errorf("service %s not found", service)
+	}
+	return instances[0], nil
+}
+
+func loadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry file: %v", err)
+	}
+
+	var cfg Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	default:
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry file: %v", err)
+	}
+	return &cfg, nil
+}
+
+func applyEnvOverrides(services map[string]ServiceConfig) map[string]ServiceConfig {
+	for _, kv := range os.Environ() {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, envPrefix) || value == "" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, envPrefix))
+		var instances []string
+		for _, u := range strings.Split(value, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				instances = append(instances, strings.TrimRight(u, "/"))
+			}
+		}
+		svc := services[name]
+		svc.Instances = instances
+		services[name] = svc
+	}
+	return services
+}
+
+func copyServices(in map[string]ServiceConfig) map[string]ServiceConfig {
+	out := make(map[string]ServiceConfig, len(in))
+	for name, svc := range in {
+		svc.Instances = append([]string{}, svc.Instances...)
+		out[name] = svc
+	}
+	return out
+}
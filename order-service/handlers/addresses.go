@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Saved addresses are owned by auth-service. Orders resolve the IDs the
+// client sends through its /me/addresses/:id endpoint, acting as the caller.
+var addressClient = &http.Client{
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+	Timeout:   5 * time.Second,
+}
+
+var (
+	errAddressNotFound  = errors.New("address not found")
+	errWrongAddressKind = errors.New("wrong address kind")
+)
+
+// authServiceURL defaults to the local auth-service.
+func authServiceURL() string {
+	if url := os.Getenv("AUTH_SERVICE_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:8081"
+}
+
+// fetchAddress loads address id from userId's address book and checks it is
+// of the given kind.
+func fetchAddress(ctx context.Context, userId, id int, kind string) (*types.Address, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/me/addresses/%d", authServiceURL(), id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-User-ID", fmt.Sprint(userId))
+	req.Header.Set("X-Request-ID", logging.RequestIDFrom(ctx))
+
+	resp, err := addressClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errAddressNotFound
+	default:
+		return nil, fmt.Errorf("auth-service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Address types.Address `json:"address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Address.Kind != kind {
+		return nil, fmt.Errorf("%w: address %d is not a %s address", errWrongAddressKind, id, kind)
+	}
+	return &body.Address, nil
+}
+
+func addressError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errAddressNotFound):
+		c.JSON(404, gin.H{"error": "Address not found"})
+	case errors.Is(err, errWrongAddressKind):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("Failed to fetch address", "error", err)
+		c.JSON(502, gin.H{"error": "Failed to fetch address"})
+	}
+}
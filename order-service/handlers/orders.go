@@ -2,20 +2,38 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"io"
 	"log"
+	"math"
 	"strconv"
 	"time"
 
 	"github.com/RohithBN/order-service/kafka"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
 	"github.com/RohithBN/shared/types"
 	"github.com/RohithBN/shared/utils"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type OrderHandler struct {
+	orders repository.OrderRepository
+	carts  repository.CartRepository
+	// sendConfirmation emails the customer once the order is placed. Tests
+	// replace it to avoid needing SMTP.
+	sendConfirmation func(email string, order *types.Order) error
+}
+
+func NewOrderHandler(orders repository.OrderRepository, carts repository.CartRepository) *OrderHandler {
+	return &OrderHandler{orders: orders, carts: carts, sendConfirmation: utils.SendOrderConfirmationEmail}
+}
+
+// TwoFacAuthMiddleware requires a recent second-factor check from the
+// caller's login session, either the emailed OTP (VerifyOTP) or a TOTP
+// step-up done in auth-service.
 func TwoFacAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDstr := c.GetHeader("X-User-ID")
@@ -30,16 +48,16 @@ func TwoFacAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 		defer cancel()
-		userVerified, err := redis.RedisClient.Get(ctx, fmt.Sprintf("user:%d:verified", userID)).Result()
+		method, err := redis.SteppedUp(ctx, userID, c.GetHeader("X-Session-ID"))
 		if err != nil {
 			log.Printf("Redis error: %v", err)
 			c.JSON(401, gin.H{"error": "Authentication required"})
 			c.Abort()
 			return
 		}
-		if userVerified != "true" {
+		if method == "" {
 			c.JSON(401, gin.H{"error": "User not verified"})
 			c.Abort()
 			return
@@ -61,6 +79,11 @@ func VerifyOTP(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "Invalid user ID format"})
 		return
 	}
+	sessionID := c.GetHeader("X-Session-ID")
+	if sessionID == "" {
+		c.JSON(401, gin.H{"error": "Session not found"})
+		return
+	}
 	// get the otp from req
 	var otpData struct {
 		OTP string `json:"otp"`
@@ -69,41 +92,29 @@ func VerifyOTP(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "Invalid request format"})
 		return
 	}
-	otp := otpData.OTP
-
-	userEmail := c.GetHeader("X-User-Email")
-	if userEmail == "" {
-		c.JSON(401, gin.H{"error": "User email not found"})
-		return
-	}
 
-	//get the otp for the user email from redis
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	//check the otp against the hashed one stored for this session
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
-	otpFromRedis, err := redis.RedisClient.Get(ctx, userEmail).Result()
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get OTP from redis"})
+	err = redis.CheckOTP(ctx, userID, sessionID, otpData.OTP)
+	switch {
+	case errors.Is(err, redis.ErrOTPNotFound):
+		c.JSON(401, gin.H{"error": "OTP not found or expired"})
 		return
-	}
-	if otpFromRedis == "" {
-		c.JSON(401, gin.H{"error": "OTP not found"})
-		return
-	}
-	//compare the otp from req and otp from redis
-	if otpFromRedis != otp {
+	case errors.Is(err, redis.ErrOTPInvalid):
 		c.JSON(401, gin.H{"error": "Invalid OTP"})
 		return
-	}
-	//if otp valid , set flag of user in redis as true (verified)
-	err = redis.RedisClient.Set(ctx, fmt.Sprintf("user:%d:verified", userID), "true", 30*time.Minute).Err()
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to set user verified flag in redis"})
+	case errors.Is(err, redis.ErrOTPTooManyAttempts):
+		c.JSON(429, gin.H{"error": "Too many invalid attempts, request a new OTP"})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to verify OTP"})
 		return
 	}
-	//delete the otp from redis
-	err = redis.RedisClient.Del(ctx, userEmail).Err()
+	//if otp valid, mark this session as verified
+	err = redis.MarkSteppedUp(ctx, userID, sessionID, redis.StepUpEmailOTP)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete OTP from redis"})
+		c.JSON(500, gin.H{"error": "Failed to set user verified flag in redis"})
 		return
 	}
 
@@ -117,14 +128,47 @@ func SendOTP(c *gin.Context) {
 		c.JSON(401, gin.H{"error": "User email not found"})
 		return
 	}
-	fmt.Println("Sending OTP to email:", userEmail)
-	err := kafka.VerifyOTPEmailProducer(userEmail)
+	userID, err := strconv.Atoi(c.GetHeader("X-User-ID"))
 	if err != nil {
-		fmt.Println("Error Sending verify mail")
+		c.JSON(401, gin.H{"error": "User ID not found"})
+		return
+	}
+	sessionID := c.GetHeader("X-Session-ID")
+	if sessionID == "" {
+		c.JSON(401, gin.H{"error": "Session not found"})
+		return
 	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+	allowed, wait, err := redis.StartOTPCooldown(ctx, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to send OTP"})
+		return
+	}
+	if !allowed {
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(429, gin.H{"error": "Please wait before requesting another OTP",
+			"retry_after": seconds})
+		return
+	}
+
+	err = kafka.VerifyOTPEmailProducer(ctx, userEmail, userID, sessionID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to produce OTP email event", "user_id", userID, "error", err)
+		// Nothing was sent, so let the user retry straight away. ctx may
+		// already have timed out.
+		if err := redis.ClearOTPCooldown(context.WithoutCancel(ctx), userID); err != nil {
+			logging.FromContext(ctx).Error("Failed to clear OTP cooldown", "user_id", userID, "error", err)
+		}
+		c.JSON(502, gin.H{"error": "Failed to send OTP"})
+		return
+	}
+	c.JSON(202, gin.H{"message": "OTP sent to your email"})
 }
 
-func CreateOrder(c *gin.Context) {
+func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	userIdStr := c.GetHeader("X-User-ID")
 	if userIdStr == "" {
 		c.JSON(401, gin.H{"error": "User ID not found"})
@@ -142,13 +186,35 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Get user's cart
-	cartCollection := utils.MongoDB.Collection("carts")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Saved addresses are optional; an empty body is fine
+	var req struct {
+		ShippingAddressId int `json:"shipping_address_id"`
+		BillingAddressId  int `json:"billing_address_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(400, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	var cart types.Cart
-	err = cartCollection.FindOne(ctx, bson.M{"userid": user_id}).Decode(&cart)
+	var shipping, billing *types.Address
+	if req.ShippingAddressId != 0 {
+		if shipping, err = fetchAddress(ctx, user_id, req.ShippingAddressId, types.AddressShipping); err != nil {
+			addressError(c, err)
+			return
+		}
+	}
+	if req.BillingAddressId != 0 {
+		if billing, err = fetchAddress(ctx, user_id, req.BillingAddressId, types.AddressBilling); err != nil {
+			addressError(c, err)
+			return
+		}
+	}
+
+	// Get user's cart
+	cart, err := h.carts.Get(ctx, user_id)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "Cart not found"})
 		return
@@ -161,24 +227,25 @@ func CreateOrder(c *gin.Context) {
 		TotalPrice: cart.TotalPrice,
 		Status:     "pending",
 		CreatedAt:  time.Now().Format(time.RFC3339),
+
+		ShippingAddress: shipping,
+		BillingAddress:  billing,
 	}
 
-	orderCollection := utils.MongoDB.Collection("orders")
-	result, err := orderCollection.InsertOne(ctx, order)
+	err = h.orders.Create(ctx, &order)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to create order"})
 		return
 	}
 
 	// Clear the cart after order creation
-	_, err = cartCollection.DeleteOne(ctx, bson.M{"userid": user_id})
+	err = h.carts.Delete(ctx, user_id)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to clear cart"})
 		return
 	}
 
-	order.OrderId = result.InsertedID.(primitive.ObjectID)
-	err = utils.SendOrderConfirmationEmail(userEmail, &order)
+	err = h.sendConfirmation(userEmail, &order)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to send order confirmation email"})
 		return
@@ -190,37 +257,48 @@ func CreateOrder(c *gin.Context) {
 	})
 }
 
-func ProcessPayment(c *gin.Context) {
+func (h *OrderHandler) ProcessPayment(c *gin.Context) {
 	var paymentInfo struct {
 		OrderId string  `json:"order_id"`
 		Amount  float64 `json:"amount"`
 		Method  string  `json:"method"` // "stripe" or "paypal"
 	}
 
+	caller, ok := callerFromRequest(c)
+	if !ok {
+		return
+	}
+
 	if err := c.BindJSON(&paymentInfo); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid payment info"})
 		return
 	}
 
-	// Simulate payment processing
-	time.Sleep(1 * time.Second)
-
-	// Update order status to paid
 	orderId, err := primitive.ObjectIDFromHex(paymentInfo.OrderId)
 	if err != nil {
 		c.JSON(400, gin.H{"error": "Invalid order ID"})
 		return
 	}
 
-	orderCollection := utils.MongoDB.Collection("orders")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	_, err = orderCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": orderId},
-		bson.M{"$set": bson.M{"status": "paid"}},
-	)
+	// Only the owner can pay for an order, there is no staff override here
+	exists, err := h.orders.Exists(ctx, orderId, caller.UserId)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch order"})
+		return
+	}
+	if !exists {
+		h.orderNotFound(c, caller, orderId, "process_payment")
+		return
+	}
+
+	// Simulate payment processing
+	time.Sleep(1 * time.Second)
+
+	// Update order status to paid
+	err = h.orders.UpdateStatus(ctx, orderId, caller.UserId, "paid")
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update order status"})
 		return
@@ -232,8 +310,38 @@ func ProcessPayment(c *gin.Context) {
 	})
 }
 
-func UpdateOrderStatus(c *gin.Context) {
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	caller, ok := callerFromRequest(c)
+	if !ok {
+		return
+	}
+
 	orderId := c.Param("orderId")
+	objectId, err := primitive.ObjectIDFromHex(orderId)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	// Status changes are fulfillment work; owners only change it by paying.
+	// Other users' orders look the same as missing ones.
+	if !caller.CanFulfill() {
+		exists, err := h.orders.Exists(ctx, objectId, caller.UserId)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch order"})
+			return
+		}
+		if !exists {
+			h.orderNotFound(c, caller, objectId, "update_order_status")
+			return
+		}
+		c.JSON(403, gin.H{"error": "Only fulfillment staff can update order status"})
+		return
+	}
+
 	var updateInfo struct {
 		Status string `json:"status"`
 	}
@@ -255,21 +363,11 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	objectId, err := primitive.ObjectIDFromHex(orderId)
-	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid order ID"})
+	err = h.orders.UpdateStatusUnscoped(ctx, objectId, updateInfo.Status)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"error": "Order not found"})
 		return
 	}
-
-	orderCollection := utils.MongoDB.Collection("orders")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err = orderCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": objectId},
-		bson.M{"$set": bson.M{"status": updateInfo.Status}},
-	)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update order status"})
 		return
@@ -281,35 +379,21 @@ func UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
-func GetOrders(c *gin.Context) {
-	userIdStr := c.GetHeader("X-User-ID")
-	if userIdStr == "" {
-		c.JSON(401, gin.H{"error": "User ID not found"})
+func (h *OrderHandler) GetOrders(c *gin.Context) {
+	caller, ok := callerFromRequest(c)
+	if !ok {
 		return
 	}
 
-	user_id, err := strconv.Atoi(userIdStr)
-	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID format"})
-		return
-	}
-
-	orderCollection := utils.MongoDB.Collection("orders")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := orderCollection.Find(ctx, bson.M{"userid": user_id})
+	// Orders are always listed for the caller only
+	orders, err := h.orders.ListByUser(ctx, caller.UserId)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to fetch orders"})
 		return
 	}
-	defer cursor.Close(ctx)
-
-	var orders []types.Order
-	if err = cursor.All(ctx, &orders); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to decode orders"})
-		return
-	}
 
 	c.JSON(200, gin.H{"orders": orders})
 }
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestOrderHandler() (*OrderHandler, *repository.MemoryOrders, *repository.MemoryCarts) {
+	orders := repository.NewMemoryOrderRepository()
+	carts := repository.NewMemoryCartRepository()
+	return NewOrderHandler(orders, carts), orders, carts
+}
+
+func createOrder(h *OrderHandler, userId string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/orders", h.CreateOrder)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("X-User-ID", userId)
+	req.Header.Set("X-User-Email", "buyer@example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateOrderFromCart(t *testing.T) {
+	h, orders, carts := newTestOrderHandler()
+	var confirmed []string
+	h.sendConfirmation = func(email string, order *types.Order) error {
+		confirmed = append(confirmed, email)
+		return nil
+	}
+	products := []types.Product{{Name: "Mug", Price: 4.5}, {Name: "Pen", Price: 1}}
+	carts.Save(context.Background(), &types.Cart{UserId: 7, Products: products, TotalPrice: 5.5})
+
+	w := createOrder(h, "7")
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Order types.Order `json:"order"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Order.Status != "pending" || resp.Order.TotalPrice != 5.5 || len(resp.Order.Products) != 2 {
+		t.Errorf("order = %+v", resp.Order)
+	}
+
+	saved, _ := orders.ListByUser(context.Background(), 7)
+	if len(saved) != 1 || saved[0].OrderId != resp.Order.OrderId {
+		t.Errorf("stored orders = %+v, want the returned order", saved)
+	}
+	if _, err := carts.Get(context.Background(), 7); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("cart still exists after ordering (err %v)", err)
+	}
+	if len(confirmed) != 1 || confirmed[0] != "buyer@example.com" {
+		t.Errorf("confirmations = %v", confirmed)
+	}
+}
+
+func TestCreateOrderWithoutCart(t *testing.T) {
+	h, orders, _ := newTestOrderHandler()
+	h.sendConfirmation = func(email string, order *types.Order) error {
+		t.Error("confirmation sent without an order")
+		return nil
+	}
+
+	if w := createOrder(h, "7"); w.Code != 404 {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+	if saved, _ := orders.ListByUser(context.Background(), 7); len(saved) != 0 {
+		t.Errorf("orders created without a cart: %+v", saved)
+	}
+}
+
+func TestCreateOrderConfirmationFails(t *testing.T) {
+	h, _, carts := newTestOrderHandler()
+	h.sendConfirmation = func(email string, order *types.Order) error {
+		return errors.New("smtp down")
+	}
+	carts.Save(context.Background(), &types.Cart{UserId: 7, Products: []types.Product{{Name: "Mug"}}})
+
+	if w := createOrder(h, "7"); w.Code != 500 {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+}
+
+func updateOrderStatus(h *OrderHandler, userId, roles string, orderId primitive.ObjectID, status string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/orders/:orderId/status", h.UpdateOrderStatus)
+
+	body := strings.NewReader(`{"status":"` + status + `"}`)
+	req := httptest.NewRequest(http.MethodPut, "/orders/"+orderId.Hex()+"/status", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userId)
+	req.Header.Set("X-User-Roles", roles)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	h, orders, _ := newTestOrderHandler()
+	order := types.Order{UserId: 7, Status: "pending"}
+	orders.Create(context.Background(), &order)
+
+	tests := []struct {
+		name    string
+		userId  string
+		roles   string
+		want    int
+		audited bool
+	}{
+		{"owner", "7", types.RoleCustomer, 403, false},
+		{"other customer", "8", types.RoleCustomer, 404, true},
+		{"staff", "9", types.RoleStaff, 200, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(orders.Audit)
+			if w := updateOrderStatus(h, tt.userId, tt.roles, order.OrderId, "shipped"); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if audited := len(orders.Audit) > before; audited != tt.audited {
+				t.Errorf("audited = %v, want %v", audited, tt.audited)
+			}
+		})
+	}
+
+	saved, _ := orders.ListByUser(context.Background(), 7)
+	if saved[0].Status != "shipped" {
+		t.Errorf("status = %q, want shipped", saved[0].Status)
+	}
+}
+
+func TestUpdateOrderStatusMissingOrder(t *testing.T) {
+	h, orders, _ := newTestOrderHandler()
+	if w := updateOrderStatus(h, "7", types.RoleCustomer, primitive.NewObjectID(), "shipped"); w.Code != 404 {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if len(orders.Audit) != 0 {
+		t.Errorf("missing order audited: %+v", orders.Audit)
+	}
+}
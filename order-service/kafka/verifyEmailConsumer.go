@@ -2,59 +2,58 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/redis"
 	"github.com/RohithBN/shared/utils"
-	"github.com/segmentio/kafka-go"
 )
 
-type OTPEmail struct {
-	Email     string `json:"email"`
-	CreatedAt string `json:"createdAt"`
+// VerifyOTPEmailConsumer generates and emails the OTPs requested on
+// OTPEmailTopic until ctx is done. Failures are retried, then dead-lettered.
+func VerifyOTPEmailConsumer(ctx context.Context, b bus.Bus) error {
+	return bus.Consume(ctx, b, OTPEmailTopic, otpEmailGroup, handleOTPEmailMessage, bus.RetryPolicyFromEnv())
 }
 
-func VerifyOTPEmailConsumer(ctx context.Context) error {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{"localhost:9092"},
-		Topic:   "send-verify-otp-email",
-		GroupID: "otp-email-group",
-	})
-	defer reader.Close()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("OTP Email consumer shutting down...")
-			return ctx.Err()
-		default:
-			m, err := reader.ReadMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return ctx.Err() // Context was canceled
-				}
-				log.Printf("Error reading message: %v", err)
-				continue
-			}
-
-			var sendOTP OTPEmail
-			err = json.Unmarshal(m.Value, &sendOTP)
-			if err != nil {
-				log.Printf("Error decoding OTP email payload: %v", err)
-				continue
-			}
-
-			fmt.Println("receiverd at sonsumer , sending to email function")
-
-			err = utils.SendOTPMail(sendOTP.Email, sendOTP.CreatedAt)
-			if err != nil {
-				log.Printf("Error sending OTP mail: %v", err)
-				continue
-			}
-
-			log.Printf("Email: %s, CreatedAt: %s", sendOTP.Email, sendOTP.CreatedAt)
-			log.Printf("Successfully sent OTP mail to %s", sendOTP.Email)
-		}
+const otpEmailGroup = "otp-email-group"
+
+func handleOTPEmailMessage(ctx context.Context, m bus.Message) error {
+	var sendOTP events.OTPRequested
+	env, err := bus.DecodeEvent(m, &sendOTP)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("error decoding OTP email payload: %v", err))
+	}
+
+	if sendOTP.UserId == 0 || sendOTP.SessionId == "" {
+		return bus.Permanent(fmt.Errorf("OTP email payload has no user or session"))
+	}
+
+	// A redelivered request would replace the emailed code with a new one
+	if done, err := redis.EventProcessed(ctx, otpEmailGroup, env.ID); err != nil {
+		return fmt.Errorf("error checking processed events: %v", err)
+	} else if done {
+		logging.FromContext(ctx).Info("Skipping already processed event", "event_id", env.ID)
+		return nil
+	}
+
+	// The code only exists in plain text here and in the email
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		return fmt.Errorf("error generating OTP: %v", err)
+	}
+	if err := redis.StoreOTP(ctx, sendOTP.UserId, sendOTP.SessionId, code); err != nil {
+		return fmt.Errorf("error storing OTP: %v", err)
+	}
+
+	if err := utils.SendOTPMail(sendOTP.Email, code); err != nil {
+		return fmt.Errorf("error sending OTP mail: %v", err)
+	}
+	if err := redis.MarkEventProcessed(ctx, otpEmailGroup, env.ID); err != nil {
+		logging.FromContext(ctx).Error("Failed to mark event processed", "event_id", env.ID, "error", err)
 	}
+
+	logging.FromContext(ctx).Info("Successfully sent OTP mail", "email", sendOTP.Email, "requested_at", sendOTP.RequestedAt)
+	return nil
 }
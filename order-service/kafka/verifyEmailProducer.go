@@ -2,35 +2,33 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"time"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
 )
 
-var writer *kafka.Writer
+// OTPEmailTopic carries events.OTPRequested to the OTP consumer.
+const OTPEmailTopic = "send-verify-otp-email"
 
-func VerifyOTPEmailProducer(email string) error {
-	createdAt := time.Now().Format("2006-01-02 15:04:05")
-	// inititalise the kafka writer
-	writer = &kafka.Writer{
-		Addr:     kafka.TCP("localhost:9092"),
-		Topic:    "send-verify-otp-email",
-		Balancer: &kafka.LeastBytes{},
-	}
+var publisher bus.Publisher
 
-	event := map[string]string{
-		"email":     email,
-		"createdAt": createdAt,
-	}
+// InitPublisher sets the bus OTP requests are published to.
+func InitPublisher(p bus.Publisher) {
+	publisher = p
+}
 
-	payload, _ := json.Marshal(event)
-	fmt.Println("Payload: ", string(payload))
-	return writer.WriteMessages(context.Background(),
-		kafka.Message{
-			Key:   []byte(email),
-			Value: payload,
-		})
+// VerifyOTPEmailProducer asks the consumer to generate and email an OTP for
+// the user's login session.
+func VerifyOTPEmailProducer(ctx context.Context, email string, userId int, sessionId string) error {
+	event := events.OTPRequested{
+		Email:       email,
+		UserId:      userId,
+		SessionId:   sessionId,
+		RequestedAt: time.Now().UTC(),
+	}
 
+	logging.FromContext(ctx).Info("Producing OTP email event", "topic", OTPEmailTopic)
+	return bus.PublishEvent(ctx, publisher, OTPEmailTopic, email, &event)
 }
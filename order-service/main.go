@@ -6,10 +6,14 @@ import (
 
 	"github.com/RohithBN/order-service/handlers"
 	"github.com/RohithBN/order-service/kafka"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/metrics"
 	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/tracing"
 	"github.com/RohithBN/shared/utils"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -18,6 +22,15 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	logging.Init("order-service")
+	events.SetSource("order-service")
+
+	shutdownTracing, err := tracing.Init(context.Background(), "order-service")
+	if err != nil {
+		log.Fatalf("Error initializing tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	if err := utils.ConnectMongoDB(); err != nil {
 		log.Fatalf("Error connecting to MongoDB: %v", err)
 	}
@@ -26,7 +39,12 @@ func main() {
 		log.Fatalf("Error connecting to Redis: %v", err)
 	}
 
-	router := gin.Default()
+	// OTP requests go through Kafka
+	msgBus := bus.NewKafka(bus.BrokersFromEnv()...)
+	defer msgBus.Close()
+	kafka.InitPublisher(msgBus)
+
+	router := logging.NewRouter("order-service")
 	router.Use(metrics.PrometheusMiddleware())
 
 	// Create a background context that won't time out
@@ -35,14 +53,20 @@ defer cancel() // Will only be called when main() exits
 
 // Start Kafka consumer
 go func() {
-    if err := kafka.VerifyOTPEmailConsumer(ctx); err != nil {
+    if err := kafka.VerifyOTPEmailConsumer(ctx, msgBus); err != nil {
         log.Printf("OTP Email consumer stopped: %v", err)
     }
 }()
 
+	orderHandler := handlers.NewOrderHandler(
+		repository.NewMongoOrderRepository(utils.MongoDB),
+		repository.NewMongoCartRepository(utils.MongoDB),
+	)
+
 	metrics.RegisterMetricsEndpoint(router)
+	metrics.RegisterHealthEndpoint(router)
 	//public routes
-	router.GET("/orders", handlers.GetOrders)
+	router.GET("/orders", orderHandler.GetOrders)
 
 	//two factor authentication routes
 	router.POST("/orders/send-otp", handlers.SendOTP)
@@ -50,9 +74,9 @@ go func() {
 
 	//protected routes
 	router.Use(handlers.TwoFacAuthMiddleware())
-	router.POST("/create-order", handlers.CreateOrder)
-	router.POST("/orders/payment", handlers.ProcessPayment)
-	router.PUT("/orders/:orderId/status", handlers.UpdateOrderStatus)
+	router.POST("/create-order", orderHandler.CreateOrder)
+	router.POST("/orders/payment", orderHandler.ProcessPayment)
+	router.PUT("/orders/:orderId/status", orderHandler.UpdateOrderStatus)
 
 	log.Printf("Order service starting on port 8084")
 	router.Run(":8084")
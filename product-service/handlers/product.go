@@ -3,26 +3,31 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
 	"github.com/RohithBN/shared/types"
-	"github.com/RohithBN/shared/utils"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetProducts(c *gin.Context) {
-	var products []types.Product
+type ProductHandler struct {
+	products repository.ProductRepository
+}
+
+func NewProductHandler(products repository.ProductRepository) *ProductHandler {
+	return &ProductHandler{products: products}
+}
 
-	collection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (h *ProductHandler) GetProducts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.D{})
+	products, err := h.products.List(ctx)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to fetch products",
 			"details": err.Error(),
@@ -30,23 +35,10 @@ func GetProducts(c *gin.Context) {
 
 		return
 	}
-	defer cursor.Close(ctx)
-	for cursor.Next(ctx) {
-		var product types.Product
-		if err := cursor.Decode(&product); err != nil {
-			c.JSON(500, gin.H{"error": "Failed to decode product"})
-			return
-		}
-		products = append(products, product)
-	}
-	if err := cursor.Err(); err != nil {
-		c.JSON(500, gin.H{"error": "Cursor error"})
-		return
-	}
 	c.JSON(200, gin.H{"products": products})
 }
 
-func AddProduct(c *gin.Context) {
+func (h *ProductHandler) AddProduct(c *gin.Context) {
 	var product types.Product
 	if err := c.BindJSON(&product); err != nil {
 		c.JSON(400, gin.H{
@@ -56,11 +48,10 @@ func AddProduct(c *gin.Context) {
 	}
 	product.CreatedAt = time.Now().Format(time.RFC3339)
 
-	collection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	_, err := collection.InsertOne(ctx, product)
+	err := h.products.Create(ctx, &product)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to add product"})
 		return
@@ -71,8 +62,7 @@ func AddProduct(c *gin.Context) {
 		"product": product,
 	})
 }
-func GetProductByID(c *gin.Context) {
-	var product types.Product
+func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	productID := c.Param("id")
 
 	objID, err := primitive.ObjectIDFromHex(productID)
@@ -82,7 +72,7 @@ func GetProductByID(c *gin.Context) {
 	}
 
 	// Check Redis cache first
-	cachedProduct, err := redis.RedisClient.Get(context.Background(), "product:"+productID).Result()
+	cachedProduct, err := redis.RedisClient.Get(c.Request.Context(), "product:"+productID).Result()
 	if err == nil {
 		// Deserialize the cached JSON string back to a product
 		var cachedProductObj types.Product
@@ -97,13 +87,16 @@ func GetProductByID(c *gin.Context) {
 		}
 	}
 
-	collection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&product)
+	product, err := h.products.Get(ctx, objID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "Product not found"})
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to fetch product"})
 		return
 	}
 	productJSON, err := json.Marshal(product)
@@ -121,7 +114,7 @@ func GetProductByID(c *gin.Context) {
 	c.JSON(200, gin.H{"product": product})
 }
 
-func UpdateProduct(c *gin.Context) {
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	var product types.Product
 	if err := c.BindJSON(&product); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid Product Details"})
@@ -137,21 +130,11 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	collection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	update := bson.M{
-		"name":          product.Name,
-		"price":         product.Price,
-		"description":   product.Description,
-		"updated_at":    product.UpdatedAt,
-		"added_to_cart": product.AddedToCart,
-		"category":      product.Category,
-		"stock":         product.Stock,
-	}
-
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": update})
+	product.ID = objID
+	err = h.products.Update(ctx, &product)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update product"})
 		return
@@ -162,7 +145,7 @@ func UpdateProduct(c *gin.Context) {
 	})
 }
 
-func DeleteProduct(c *gin.Context) {
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	productID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(productID)
 	if err != nil {
@@ -170,17 +153,16 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	collection := utils.MongoDB.Collection("products")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete product"})
+	err = h.products.Delete(ctx, objID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"error": "Product not found"})
 		return
 	}
-	if result.DeletedCount == 0 {
-		c.JSON(404, gin.H{"error": "Product not found"})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete product"})
 		return
 	}
 
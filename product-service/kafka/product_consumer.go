@@ -2,74 +2,59 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"fmt"
 	"time"
-	"github.com/RohithBN/shared/utils"
-	"github.com/segmentio/kafka-go"
-	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func ConsumeCartAddItem()  {
-	ConsumeCartAddItemWithContext(context.Background())
+// ConsumeCartAddItemWithContext takes the items added to carts out of stock
+// until ctx is done. Failed updates are retried, then dead-lettered.
+func ConsumeCartAddItemWithContext(ctx context.Context, b bus.Bus, products repository.ProductRepository, processed repository.ProcessedEvents) error {
+	handler := func(ctx context.Context, m bus.Message) error {
+		return handleCartAddItemMessage(ctx, products, processed, m)
+	}
+	return bus.Consume(ctx, b, "cart-add-item-topic", cartGroup, handler, bus.RetryPolicyFromEnv())
 }
 
-func ConsumeCartAddItemWithContext(ctx context.Context) error {
-
-		reader := kafka.NewReader(kafka.ReaderConfig{
-			Brokers: []string{"localhost:9092"},
-			Topic:   "cart-add-item-topic",
-			GroupID: "cart-group",
-		})
-
-		defer reader.Close()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Println("Cart consumer shutting down...")
-				return nil
-			default:
-				m, err := reader.ReadMessage(ctx)
-				if err != nil {
-					log.Printf("Error reading message: %v", err)
-					continue
-				}
-				var event struct {
-					Quantity  int    `json:"quantity"`
-					ProductId string `json:"productId"`
-					UserId    int    `json:"userId"`
-				}
-				if err := json.Unmarshal(m.Value, &event); err != nil {
-					log.Printf("Error unmarshalling message: %v", err)
-					continue
-				}
-				log.Printf("Received message: %s", string(m.Value))
-				log.Printf("Quantity: %d, ProductId: %s, UserId: %d", event.Quantity, event.ProductId, event.UserId)
-
-				//logic to update the product stock
-
-				productCollection := utils.MongoDB.Collection("products")
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				// convert productId(stirng) to ObjectID
-				objectId, err := primitive.ObjectIDFromHex(event.ProductId)
-				if err != nil {
-					log.Printf("Error converting productId to ObjectID: %v", err)
-					continue
-				}
-
-				//update the product stock
-
-				_, err = productCollection.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$inc": bson.M{"stock": -event.Quantity}})
-				if err != nil {
-					log.Printf("Error updating product stock: %v", err)
-					continue
-				}
-				log.Printf("Product stock updated successfully")
-			}
-		}
-
-
+const cartGroup = "cart-group"
+
+func handleCartAddItemMessage(ctx context.Context, products repository.ProductRepository, processed repository.ProcessedEvents, m bus.Message) error {
+	logger := logging.FromContext(ctx)
+
+	var event events.CartItemAdded
+	env, err := bus.DecodeEvent(m, &event)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("error unmarshalling message: %v", err))
+	}
+	logger.Info("Received message", "topic", m.Topic, "quantity", event.Quantity, "product_id", event.ProductId, "user_id", event.UserId, "event_id", env.ID)
+
+	//logic to update the product stock
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	// convert productId(stirng) to ObjectID
+	objectId, err := primitive.ObjectIDFromHex(event.ProductId)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("error converting productId %s to ObjectID: %v", event.ProductId, err))
+	}
+
+	//update the product stock, at most once per event even if it is redelivered
+
+	applied, err := processed.Once(ctx, cartGroup, env.ID, func(ctx context.Context) error {
+		return products.AdjustStock(ctx, objectId, -event.Quantity)
+	})
+	if err != nil {
+		return fmt.Errorf("error updating product stock: %v", err)
+	}
+	if !applied {
+		logger.Info("Skipping already processed event", "event_id", env.ID)
+		return nil
+	}
+	logger.Info("Product stock updated successfully", "product_id", event.ProductId)
+	return nil
 }
@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cartkafka "github.com/RohithBN/cart-service/kafka"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/outbox"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/types"
+)
+
+// TestCartAddItemTakesStock runs the cart -> product stock flow end to end:
+// cart-service stores the event in its outbox, the relay publishes it and
+// this consumer adjusts the stock.
+func TestCartAddItemTakesStock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	products := repository.NewMemoryProductRepository()
+	product := types.Product{Name: "Mug", Price: 4.5, Stock: 10}
+	products.Create(ctx, &product)
+
+	b := bus.NewMemory()
+	consumerCtx, stopConsumer := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- ConsumeCartAddItemWithContext(consumerCtx, b, products, repository.NewMemoryProcessedEvents())
+	}()
+	defer func() {
+		stopConsumer()
+		<-done
+	}()
+	if err := b.WaitSubscribed(ctx, cartkafka.CartAddItemTopic, cartGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	cartOutbox := repository.NewMemoryOutbox()
+	cartkafka.InitOutbox(cartOutbox)
+	for _, quantity := range []int{2, 3} {
+		if err := cartkafka.ProduceCartAddItem(ctx, quantity, product.ID.Hex(), 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Records with the same key are relayed one per batch
+	relay := outbox.NewRelay(cartOutbox, b)
+	for len(cartOutbox.Pending()) > 0 {
+		if _, err := relay.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(t, products, product); got != 5 {
+		t.Fatalf("stock = %d, want 5", got)
+	}
+
+	// A redelivered event must not take the items out of stock twice
+	published, err := b.ReadAll(ctx, cartkafka.CartAddItemTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(ctx, cartkafka.CartAddItemTopic, published[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(t, products, product); got != 5 {
+		t.Errorf("stock after redelivery = %d, want 5", got)
+	}
+}
+
+func stock(t *testing.T, products repository.ProductRepository, product types.Product) int {
+	t.Helper()
+	p, err := products.Get(context.Background(), product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Stock
+}
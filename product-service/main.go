@@ -6,10 +6,13 @@ import (
 
 	"github.com/RohithBN/product-service/handlers"
 	"github.com/RohithBN/product-service/kafka"
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/logging"
 	"github.com/RohithBN/shared/metrics"
 	"github.com/RohithBN/shared/redis"
+	"github.com/RohithBN/shared/repository"
+	"github.com/RohithBN/shared/tracing"
 	"github.com/RohithBN/shared/utils"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -18,6 +21,14 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	logging.Init("product-service")
+
+	shutdownTracing, err := tracing.Init(context.Background(), "product-service")
+	if err != nil {
+		log.Fatalf("Error initializing tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	if err := utils.ConnectMongoDB(); err != nil {
 		log.Fatalf("Error connecting to MongoDB: %v", err)
 	}
@@ -27,25 +38,36 @@ func main() {
 		log.Fatalf("Error connecting to Redis: %v", err)
 	}
 
+	if err := repository.EnsureProcessedEventsTTL(context.Background(), utils.MongoDB); err != nil {
+		log.Fatalf("Error creating processed_events TTL index: %v", err)
+	}
+
+	products := repository.NewMongoProductRepository(utils.MongoDB)
+
+	msgBus := bus.NewKafka(bus.BrokersFromEnv()...)
+	defer msgBus.Close()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go func() {
-		if err := kafka.ConsumeCartAddItemWithContext(ctx); err != nil {
+		if err := kafka.ConsumeCartAddItemWithContext(ctx, msgBus, products, repository.NewMongoProcessedEvents(utils.MongoDB)); err != nil {
 			log.Printf("Error starting Kafka consumer: %v", err)
 		}
 	}()
 
-	router := gin.Default()
+	router := logging.NewRouter("product-service")
 
 	router.Use(metrics.PrometheusMiddleware())
 
 	metrics.RegisterMetricsEndpoint(router)
+	metrics.RegisterHealthEndpoint(router)
 
-	router.GET("/products", handlers.GetProducts)
-	router.POST("/add-product", handlers.AddProduct)
-	router.GET("/products/:id", handlers.GetProductByID)
-	router.PUT("/update-product/:id", handlers.UpdateProduct)
-	router.DELETE("/delete-product/:id", handlers.DeleteProduct)
+	productHandler := handlers.NewProductHandler(products)
+	router.GET("/products", productHandler.GetProducts)
+	router.POST("/add-product", productHandler.AddProduct)
+	router.GET("/products/:id", productHandler.GetProductByID)
+	router.PUT("/update-product/:id", productHandler.UpdateProduct)
+	router.DELETE("/delete-product/:id", productHandler.DeleteProduct)
 
 	log.Printf("Product service starting on port 8082")
 	router.Run(":8082")
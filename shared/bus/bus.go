@@ -0,0 +1,170 @@
+// Package bus is the message bus the services publish events to and consume
+// them from. Kafka is used in production; Memory is an in-process broker with
+// the same ordering and consumer-group behaviour for tests.
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/metrics"
+	"github.com/RohithBN/shared/tracing"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message is a Kafka message. Publishers only need to set Key, Value and
+// Headers; Topic, Partition and Offset are filled in on delivery.
+type Message = kafka.Message
+
+type Header = kafka.Header
+
+type Publisher interface {
+	// Publish appends msgs to topic. Messages with the same key are
+	// delivered in the order they were published.
+	Publish(ctx context.Context, topic string, msgs ...Message) error
+	Close() error
+}
+
+// Handler processes one message. A message is only committed once its
+// handler returns nil; on error it is delivered again after a backoff, so
+// handlers must be idempotent. Consume turns failures into retries instead.
+type Handler func(ctx context.Context, m Message) error
+
+type Subscriber interface {
+	// Subscribe consumes topic as a member of group until ctx is done. Each
+	// message is handled by one member of every group subscribed to topic,
+	// at least once.
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+}
+
+type Bus interface {
+	Publisher
+	Subscriber
+}
+
+// BrokersFromEnv reads the comma-separated KAFKA_BROKERS, defaulting to a
+// local broker.
+func BrokersFromEnv() []string {
+	if v := os.Getenv("KAFKA_BROKERS"); v != "" {
+		return strings.Split(v, ",")
+	}
+	return []string{"localhost:9092"}
+}
+
+// PublishEvent validates e, wraps it in an events.Envelope and publishes it
+// to topic under key in the topic's encoding, carrying the request ID and
+// trace context from ctx in the message headers.
+func PublishEvent(ctx context.Context, p Publisher, topic, key string, e events.Event) (err error) {
+	ctx, span := tracing.StartProducerSpan(ctx, topic)
+	defer func() {
+		tracing.End(span, err)
+		metrics.KafkaOperations.WithLabelValues(topic, "produce", status(err)).Inc()
+	}()
+
+	msg, err := NewEventMessage(ctx, topic, key, e)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, topic, msg)
+}
+
+// NewEventMessage builds the message PublishEvent would publish, for callers
+// that hand it to an outbox instead.
+func NewEventMessage(ctx context.Context, topic, key string, e events.Event) (Message, error) {
+	env, err := events.New(e)
+	if err != nil {
+		return Message{}, err
+	}
+	env.TraceParent, env.TraceState = tracing.TraceContext(ctx)
+
+	msg := Message{
+		Key:     []byte(key),
+		Headers: logging.KafkaHeaders(ctx),
+	}
+	switch encoding := EncodingForTopic(topic); encoding {
+	case EncodingJSON:
+		msg.Value, err = json.Marshal(env)
+	case EncodingCloudEvents, EncodingProtobuf:
+		var headers []Header
+		msg.Value, headers, err = encodeCloudEvent(env, e, encoding)
+		msg.Headers = append(msg.Headers, headers...)
+	default:
+		err = fmt.Errorf("unknown encoding %q for topic %s", encoding, topic)
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	tracing.InjectKafka(ctx, &msg)
+	return msg, nil
+}
+
+// DecodeEvent decodes the event in m into e, upgrading older versions of it.
+// It reads every encoding: CloudEvents are recognised by their
+// ce_specversion header and decoded by content type. Messages published
+// without an envelope get their position on the original topic as ID, which
+// is equally stable across redeliveries.
+func DecodeEvent(m Message, e events.Event) (*events.Envelope, error) {
+	if header(m.Headers, HeaderCESpecVersion) != "" {
+		return decodeCloudEvent(m, e)
+	}
+	env, err := events.Decode(m.Value, e)
+	if err != nil {
+		return nil, err
+	}
+	if env.ID == "" {
+		if topic := header(m.Headers, HeaderOriginalTopic); topic != "" {
+			env.ID = fmt.Sprintf("%s/%s/%s", topic, header(m.Headers, HeaderOriginalPartition), header(m.Headers, HeaderOriginalOffset))
+		} else {
+			env.ID = fmt.Sprintf("%s/%d/%d", m.Topic, m.Partition, m.Offset)
+		}
+	}
+	return env, nil
+}
+
+const maxRedeliveryBackoff = 30 * time.Second
+
+// deliver calls handler until it succeeds or ctx is done, doubling backoff
+// between attempts.
+func deliver(ctx context.Context, handler Handler, m Message, backoff time.Duration) error {
+	for {
+		if err := handler(ctx, m); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRedeliveryBackoff {
+			backoff = maxRedeliveryBackoff
+		}
+	}
+}
+
+// instrument runs handler in the producer's trace and request ID, and logs
+// and counts failures.
+func instrument(handler Handler) Handler {
+	return func(ctx context.Context, m Message) error {
+		ctx, span := tracing.StartConsumerSpan(logging.ContextFromMessage(ctx, m), m)
+		err := handler(ctx, m)
+		tracing.End(span, err)
+		metrics.KafkaOperations.WithLabelValues(m.Topic, "consume", status(err)).Inc()
+		if err != nil {
+			logging.FromContext(ctx).Error("Error handling message", "topic", m.Topic, "error", err)
+		}
+		return err
+	}
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,115 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RohithBN/shared/events"
+)
+
+// Encodings a topic's events can be published in.
+const (
+	// EncodingJSON puts the whole events.Envelope in the message value. It
+	// is the default, and what consumers predating CloudEvents understand.
+	EncodingJSON = "json"
+	// EncodingCloudEvents is CloudEvents 1.0 binary mode: the envelope
+	// attributes go in ce_ headers and the value is the JSON payload alone.
+	EncodingCloudEvents = "cloudevents"
+	// EncodingProtobuf is CloudEvents binary mode with a Protobuf payload.
+	EncodingProtobuf = "protobuf"
+)
+
+// CloudEvents Kafka protocol binding headers.
+const (
+	HeaderContentType   = "content-type"
+	HeaderCESpecVersion = "ce_specversion"
+	HeaderCEID          = "ce_id"
+	HeaderCESource      = "ce_source"
+	HeaderCEType        = "ce_type"
+	HeaderCETime        = "ce_time"
+	HeaderCEDataSchema  = "ce_dataschema"
+	HeaderCETraceParent = "ce_traceparent"
+	HeaderCETraceState  = "ce_tracestate"
+)
+
+const ceSpecVersion = "1.0"
+
+// EncodingForTopic reads the encoding for topic from EVENT_ENCODINGS, a
+// comma-separated list of topic=encoding pairs. Unlisted topics use
+// EncodingJSON.
+func EncodingForTopic(topic string) string {
+	for _, pair := range strings.Split(os.Getenv("EVENT_ENCODINGS"), ",") {
+		t, encoding, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && t == topic {
+			return encoding
+		}
+	}
+	return EncodingJSON
+}
+
+// encodeCloudEvent returns the value and ce_ headers of env in binary mode.
+// The event's version is carried in ce_dataschema.
+func encodeCloudEvent(env *events.Envelope, e events.Event, encoding string) ([]byte, []Header, error) {
+	value, contentType := []byte(env.Data), events.ContentTypeJSON
+	if encoding == EncodingProtobuf {
+		var err error
+		if value, err = events.MarshalProto(e); err != nil {
+			return nil, nil, err
+		}
+		contentType = events.ContentTypeProtobuf
+	}
+
+	source := env.Source
+	if source == "" {
+		source = "unknown"
+	}
+	headers := []Header{
+		{Key: HeaderCESpecVersion, Value: []byte(ceSpecVersion)},
+		{Key: HeaderCEID, Value: []byte(env.ID)},
+		{Key: HeaderCESource, Value: []byte(source)},
+		{Key: HeaderCEType, Value: []byte(env.Type)},
+		{Key: HeaderCETime, Value: []byte(env.OccurredAt.Format(time.RFC3339Nano))},
+		{Key: HeaderCEDataSchema, Value: []byte(events.SchemaID(env.Type, env.Version))},
+		{Key: HeaderContentType, Value: []byte(contentType)},
+	}
+	if env.TraceParent != "" {
+		headers = append(headers, Header{Key: HeaderCETraceParent, Value: []byte(env.TraceParent)})
+	}
+	if env.TraceState != "" {
+		headers = append(headers, Header{Key: HeaderCETraceState, Value: []byte(env.TraceState)})
+	}
+	return value, headers, nil
+}
+
+// decodeCloudEvent decodes a binary mode CloudEvent into e.
+func decodeCloudEvent(m Message, e events.Event) (*events.Envelope, error) {
+	if v := header(m.Headers, HeaderCESpecVersion); v != ceSpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents specversion %q", v)
+	}
+	env := &events.Envelope{
+		ID:          header(m.Headers, HeaderCEID),
+		Type:        header(m.Headers, HeaderCEType),
+		Source:      header(m.Headers, HeaderCESource),
+		TraceParent: header(m.Headers, HeaderCETraceParent),
+		TraceState:  header(m.Headers, HeaderCETraceState),
+	}
+	if env.ID == "" || env.Type == "" {
+		return nil, fmt.Errorf("CloudEvent without ce_id or ce_type")
+	}
+	if t := header(m.Headers, HeaderCETime); t != "" {
+		env.OccurredAt, _ = time.Parse(time.RFC3339Nano, t)
+	}
+	// Events from producers outside this repo may carry no schema; they
+	// are read as version 1
+	if eventType, version, ok := events.ParseSchemaID(header(m.Headers, HeaderCEDataSchema)); ok && eventType == env.Type {
+		env.Version = version
+	}
+
+	contentType := header(m.Headers, HeaderContentType)
+	if contentType == "" || contentType == events.ContentTypeJSON {
+		env.Data = append([]byte(nil), m.Value...)
+	}
+	return env, events.DecodeData(env, contentType, m.Value, e)
+}
@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/RohithBN/shared/events"
+	"github.com/RohithBN/shared/metrics"
+)
+
+// Browser reads a topic without consuming it.
+type Browser interface {
+	ReadAll(ctx context.Context, topic string) ([]Message, error)
+}
+
+const dlqUsage = `usage: dlq <command>
+
+commands:
+  list <topic>                           show the dead-lettered messages of topic
+  replay <topic> [partition:offset ...]  republish all, or the given, messages to topic
+`
+
+// DLQCommand runs the dlq tool described by args, writing output to out.
+// Topics can be given with or without the .dlq suffix.
+func DLQCommand(ctx context.Context, b interface {
+	Publisher
+	Browser
+}, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		fmt.Fprint(out, dlqUsage)
+		return fmt.Errorf("missing command or topic")
+	}
+	topic := strings.TrimSuffix(args[1], ".dlq")
+	messages, err := b.ReadAll(ctx, DLQTopic(topic))
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "POSITION\tKEY\tFAILED AT\tATTEMPTS\tERROR\tVALUE")
+		for _, m := range messages {
+			fmt.Fprintf(w, "%d:%d\t%s\t%s\t%s\t%s\t%s\n", m.Partition, m.Offset, m.Key,
+				header(m.Headers, HeaderFailedAt), header(m.Headers, HeaderRetryAttempt),
+				header(m.Headers, HeaderError), preview(m))
+		}
+		return w.Flush()
+	case "replay":
+		selected, err := selectMessages(messages, args[2:])
+		if err != nil {
+			return err
+		}
+		for _, m := range selected {
+			if err := replay(ctx, b, topic, m); err != nil {
+				return fmt.Errorf("failed to replay %d:%d: %v", m.Partition, m.Offset, err)
+			}
+			fmt.Fprintf(out, "Replayed %d:%d to %s\n", m.Partition, m.Offset, topic)
+		}
+		if len(selected) == 0 {
+			fmt.Fprintln(out, "Nothing to replay")
+		}
+		return nil
+	default:
+		fmt.Fprint(out, dlqUsage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// selectMessages picks the messages at the given partition:offset positions,
+// or all of them when none are given.
+func selectMessages(messages []Message, positions []string) ([]Message, error) {
+	if len(positions) == 0 {
+		return messages, nil
+	}
+	byPosition := map[string]Message{}
+	for _, m := range messages {
+		byPosition[fmt.Sprintf("%d:%d", m.Partition, m.Offset)] = m
+	}
+	var selected []Message
+	for _, pos := range positions {
+		m, ok := byPosition[pos]
+		if !ok {
+			return nil, fmt.Errorf("no message at %s", pos)
+		}
+		selected = append(selected, m)
+	}
+	return selected, nil
+}
+
+// replay republishes m to topic with the retry and dead-letter headers
+// removed, so it gets a fresh set of retries.
+func replay(ctx context.Context, b Publisher, topic string, m Message) error {
+	var headers []Header
+	for _, h := range m.Headers {
+		switch h.Key {
+		case HeaderRetryAttempt, HeaderRetryAt, HeaderOriginalTopic, HeaderOriginalPartition,
+			HeaderOriginalOffset, HeaderError, HeaderFailedAt:
+		default:
+			headers = append(headers, h)
+		}
+	}
+	err := b.Publish(ctx, topic, Message{Key: m.Key, Value: m.Value, Headers: headers})
+	metrics.KafkaOperations.WithLabelValues(topic, "replay", status(err)).Inc()
+	return err
+}
+
+// preview shows the start of a JSON value, or just the size of a binary one.
+func preview(m Message) string {
+	if ct := header(m.Headers, HeaderContentType); ct != "" && !strings.HasPrefix(ct, events.ContentTypeJSON) {
+		return fmt.Sprintf("<%s, %d bytes>", ct, len(m.Value))
+	}
+	return truncate(string(m.Value), 80)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,117 @@
+package bus
+
+import (
+	"context"
+	"time"
+
+	"github.com/RohithBN/shared/logging"
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka publishes and consumes through a Kafka cluster.
+type Kafka struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+func NewKafka(brokers ...string) *Kafka {
+	return &Kafka{
+		brokers: brokers,
+		// Hashing on the key keeps each key on one partition, which is
+		// what gives per-key ordering.
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Balancer: &kafka.Hash{},
+			// Retry and dead-letter topics are created on first use
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+func (k *Kafka) Publish(ctx context.Context, topic string, msgs ...Message) error {
+	for i := range msgs {
+		msgs[i].Topic = topic
+	}
+	return k.writer.WriteMessages(ctx, msgs...)
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
+
+func (k *Kafka) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: k.brokers,
+		Topic:   topic,
+		GroupID: group,
+	})
+	defer reader.Close()
+
+	handler = instrument(handler)
+	logger := logging.FromContext(ctx)
+	for {
+		// Offsets are committed only after the handler succeeds, so a crash
+		// mid-message means it is delivered again rather than lost.
+		m, err := reader.FetchMessage(ctx)
+		if err == nil {
+			err = deliver(ctx, handler, m, time.Second)
+		}
+		if err == nil {
+			err = reader.CommitMessages(ctx, m)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Info("Consumer shutting down", "topic", topic, "group", group)
+				return nil
+			}
+			logger.Error("Error consuming message", "topic", topic, "error", err)
+		}
+	}
+}
+
+// ReadAll returns every message currently in topic, without joining a
+// consumer group or committing offsets.
+func (k *Kafka) ReadAll(ctx context.Context, topic string) ([]Message, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", k.brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	for _, p := range partitions {
+		leader, err := kafka.DialLeader(ctx, "tcp", k.brokers[0], topic, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		first, last, err := leader.ReadOffsets()
+		leader.Close()
+		if err != nil {
+			return nil, err
+		}
+		if first >= last {
+			continue
+		}
+
+		reader := kafka.NewReader(kafka.ReaderConfig{Brokers: k.brokers, Topic: topic, Partition: p.ID})
+		if err := reader.SetOffset(first); err != nil {
+			reader.Close()
+			return nil, err
+		}
+		for offset := first; offset < last; {
+			m, err := reader.ReadMessage(ctx)
+			if err != nil {
+				reader.Close()
+				return nil, err
+			}
+			messages = append(messages, m)
+			offset = m.Offset + 1
+		}
+		reader.Close()
+	}
+	return messages, nil
+}
@@ -0,0 +1,194 @@
+package bus
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process broker. Topics are split into partitions by key
+// hash like Kafka's, every consumer group sees every message, and within a
+// group a partition is handled by one member at a time, so per-key order is
+// kept however many members subscribe.
+type Memory struct {
+	partitions int
+
+	mu      sync.Mutex
+	topics  map[string][][]Message // topic -> partition -> log
+	groups  map[string]*memoryGroup
+	changed chan struct{} // closed and replaced whenever state changes
+}
+
+type memoryGroup struct {
+	topic  string
+	next   map[int]int64 // next offset to deliver, per partition
+	active map[int]bool  // partitions a member is currently handling
+}
+
+// NewMemory returns a broker with four partitions per topic.
+func NewMemory() *Memory {
+	return &Memory{
+		partitions: 4,
+		topics:     map[string][][]Message{},
+		groups:     map[string]*memoryGroup{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *Memory) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *Memory) log(topic string) [][]Message {
+	if _, ok := b.topics[topic]; !ok {
+		b.topics[topic] = make([][]Message, b.partitions)
+	}
+	return b.topics[topic]
+}
+
+func (b *Memory) partition(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(b.partitions))
+}
+
+func (b *Memory) Publish(ctx context.Context, topic string, msgs ...Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	log := b.log(topic)
+	for _, m := range msgs {
+		p := b.partition(m.Key)
+		m.Topic = topic
+		m.Partition = p
+		m.Offset = int64(len(log[p]))
+		m.Time = time.Now()
+		log[p] = append(log[p], m)
+	}
+	b.notify()
+	return nil
+}
+
+func (b *Memory) Close() error { return nil }
+
+// claim finds the next message for group that no other member is handling
+// and marks its partition active.
+func (b *Memory) claim(g *memoryGroup) (Message, bool) {
+	for p, msgs := range b.log(g.topic) {
+		if !g.active[p] && g.next[p] < int64(len(msgs)) {
+			g.active[p] = true
+			return msgs[g.next[p]], true
+		}
+	}
+	return Message{}, false
+}
+
+func (b *Memory) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	handler = instrument(handler)
+
+	b.mu.Lock()
+	g, ok := b.groups[topic+"/"+group]
+	if !ok {
+		g = &memoryGroup{topic: topic, next: map[int]int64{}, active: map[int]bool{}}
+		b.groups[topic+"/"+group] = g
+		b.notify()
+	}
+	b.mu.Unlock()
+
+	for ctx.Err() == nil {
+		b.mu.Lock()
+		m, ok := b.claim(g)
+		changed := b.changed
+		b.mu.Unlock()
+
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changed:
+				continue
+			}
+		}
+
+		// The partition stays claimed while the message is redelivered, so
+		// nothing behind it is handled first.
+		err := deliver(ctx, handler, m, 10*time.Millisecond)
+
+		b.mu.Lock()
+		if err == nil {
+			g.next[m.Partition] = m.Offset + 1
+		}
+		g.active[m.Partition] = false
+		b.notify()
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// ReadAll returns every message published to topic, partition by partition.
+func (b *Memory) ReadAll(ctx context.Context, topic string) ([]Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var messages []Message
+	for _, msgs := range b.log(topic) {
+		messages = append(messages, msgs...)
+	}
+	return messages, nil
+}
+
+// WaitSubscribed blocks until group has subscribed to topic, or ctx is done.
+// Call it before WaitIdle when the subscriber is started in a goroutine, as
+// WaitIdle only waits for groups that already exist.
+func (b *Memory) WaitSubscribed(ctx context.Context, topic, group string) error {
+	for {
+		b.mu.Lock()
+		_, ok := b.groups[topic+"/"+group]
+		changed := b.changed
+		b.mu.Unlock()
+
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// WaitIdle blocks until every subscribed group has handled every published
+// message, or ctx is done.
+func (b *Memory) WaitIdle(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		idle := true
+		for _, g := range b.groups {
+			for p, msgs := range b.log(g.topic) {
+				if g.active[p] || g.next[p] < int64(len(msgs)) {
+					idle = false
+				}
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if idle {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+var (
+	_ Bus     = (*Memory)(nil)
+	_ Bus     = (*Kafka)(nil)
+	_ Browser = (*Memory)(nil)
+	_ Browser = (*Kafka)(nil)
+)
@@ -0,0 +1,146 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startGroup subscribes members handlers to topic as group and waits until
+// they are registered. The subscriptions end with the test.
+func startGroup(t *testing.T, b *Memory, topic, group string, members int, handler Handler) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	for i := 0; i < members; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Subscribe(ctx, topic, group, handler)
+		}()
+	}
+	if err := b.WaitSubscribed(ctx, topic, group); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitIdle(t *testing.T, b *Memory) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.WaitIdle(ctx); err != nil {
+		t.Fatalf("bus not idle: %v", err)
+	}
+}
+
+// recorder collects the sequence numbers handled per key.
+type recorder struct {
+	mu   sync.Mutex
+	seen map[string][]int
+}
+
+func newRecorder() *recorder { return &recorder{seen: map[string][]int{}} }
+
+func (r *recorder) handle(ctx context.Context, m Message) error {
+	n, err := strconv.Atoi(string(m.Value))
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.seen[string(m.Key)] = append(r.seen[string(m.Key)], n)
+	r.mu.Unlock()
+	return nil
+}
+
+// check fails unless every key was handled exactly once per message, in the
+// order it was published.
+func (r *recorder) check(t *testing.T, keys, perKey int) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.seen) != keys {
+		t.Errorf("got %d keys, want %d", len(r.seen), keys)
+	}
+	for key, seq := range r.seen {
+		if len(seq) != perKey {
+			t.Errorf("key %s: handled %d messages, want %d", key, len(seq), perKey)
+			continue
+		}
+		for i, n := range seq {
+			if n != i {
+				t.Errorf("key %s: handled out of order: %v", key, seq)
+				break
+			}
+		}
+	}
+}
+
+func publishSequence(t *testing.T, b *Memory, topic string, keys, perKey int) {
+	t.Helper()
+	for i := 0; i < perKey; i++ {
+		for k := 0; k < keys; k++ {
+			m := Message{Key: []byte(fmt.Sprintf("key-%d", k)), Value: []byte(strconv.Itoa(i))}
+			if err := b.Publish(context.Background(), topic, m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMemoryKeepsPerKeyOrderAcrossMembers(t *testing.T) {
+	b := NewMemory()
+	rec := newRecorder()
+	startGroup(t, b, "orders", "billing", 3, func(ctx context.Context, m Message) error {
+		// Give other members a chance to pick up later messages
+		time.Sleep(time.Millisecond)
+		return rec.handle(ctx, m)
+	})
+
+	publishSequence(t, b, "orders", 6, 20)
+	waitIdle(t, b)
+	rec.check(t, 6, 20)
+}
+
+func TestMemoryDeliversToEveryGroup(t *testing.T) {
+	b := NewMemory()
+	billing, shipping := newRecorder(), newRecorder()
+	startGroup(t, b, "orders", "billing", 2, billing.handle)
+	startGroup(t, b, "orders", "shipping", 2, shipping.handle)
+
+	publishSequence(t, b, "orders", 4, 10)
+	waitIdle(t, b)
+	billing.check(t, 4, 10)
+	shipping.check(t, 4, 10)
+}
+
+func TestMemoryRedeliversInOrder(t *testing.T) {
+	b := NewMemory()
+	rec := newRecorder()
+	var mu sync.Mutex
+	failed := false
+	startGroup(t, b, "orders", "billing", 2, func(ctx context.Context, m Message) error {
+		mu.Lock()
+		fail := !failed && string(m.Key) == "key-0" && string(m.Value) == "0"
+		failed = failed || fail
+		mu.Unlock()
+		if fail {
+			return errors.New("temporary failure")
+		}
+		return rec.handle(ctx, m)
+	})
+
+	publishSequence(t, b, "orders", 2, 5)
+	waitIdle(t, b)
+	rec.check(t, 2, 5)
+	if !failed {
+		t.Error("the first message was never failed")
+	}
+}
@@ -0,0 +1,171 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/metrics"
+)
+
+// Headers added to messages moved to retry and dead-letter topics.
+const (
+	HeaderRetryAttempt      = "x-retry-attempt"
+	HeaderRetryAt           = "x-retry-at"
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+// RetryTopic is the topic holding the n-th retry of messages from topic.
+func RetryTopic(topic string, n int) string { return fmt.Sprintf("%s.retry.%d", topic, n) }
+
+// DLQTopic is where messages from topic go once they can't be processed.
+func DLQTopic(topic string) string { return topic + ".dlq" }
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one retrying won't fix, such as a payload that
+// doesn't decode, so the message goes straight to the dead-letter topic.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+type RetryPolicy struct {
+	// Attempts is how many times a failed message is retried before it is
+	// dead-lettered.
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for each one
+	// after it.
+	Backoff time.Duration
+}
+
+// RetryPolicyFromEnv reads KAFKA_RETRY_ATTEMPTS (default 3) and
+// KAFKA_RETRY_BACKOFF (default 5s).
+func RetryPolicyFromEnv() RetryPolicy {
+	policy := RetryPolicy{Attempts: 3, Backoff: 5 * time.Second}
+	if n, err := strconv.Atoi(os.Getenv("KAFKA_RETRY_ATTEMPTS")); err == nil && n >= 0 {
+		policy.Attempts = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("KAFKA_RETRY_BACKOFF")); err == nil && d > 0 {
+		policy.Backoff = d
+	}
+	return policy
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
+
+// Consume runs handler for topic as group. Failed messages are republished
+// to RetryTopic(topic, n) and handled again after the backoff; permanent
+// failures, and messages that fail every retry, go to DLQTopic(topic) with
+// the error in their headers.
+func Consume(ctx context.Context, b Bus, topic, group string, handler Handler, policy RetryPolicy) error {
+	topics := []string{topic}
+	for n := 1; n <= policy.Attempts; n++ {
+		topics = append(topics, RetryTopic(topic, n))
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(topics))
+	for i, t := range topics {
+		wg.Add(1)
+		go func(i int, t string) {
+			defer wg.Done()
+			errs[i] = b.Subscribe(ctx, t, group, func(ctx context.Context, m Message) error {
+				return handleWithRetry(ctx, b, topic, m, handler, policy)
+			})
+		}(i, t)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func handleWithRetry(ctx context.Context, b Publisher, topic string, m Message, handler Handler, policy RetryPolicy) error {
+	attempt, _ := strconv.Atoi(header(m.Headers, HeaderRetryAttempt))
+	if attempt > 0 {
+		// Everything in a retry topic has the same delay, so waiting here
+		// doesn't hold up messages that are due sooner.
+		if at, err := time.Parse(time.RFC3339Nano, header(m.Headers, HeaderRetryAt)); err == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(at)):
+			}
+		}
+	}
+
+	err := handler(ctx, m)
+	if err == nil {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+	out := Message{Key: m.Key, Value: m.Value, Headers: append([]Header(nil), m.Headers...)}
+	if header(out.Headers, HeaderOriginalTopic) == "" {
+		out.Headers = setHeader(out.Headers, HeaderOriginalTopic, topic)
+		out.Headers = setHeader(out.Headers, HeaderOriginalPartition, strconv.Itoa(m.Partition))
+		out.Headers = setHeader(out.Headers, HeaderOriginalOffset, strconv.FormatInt(m.Offset, 10))
+	}
+	out.Headers = setHeader(out.Headers, HeaderError, err.Error())
+	out.Headers = setHeader(out.Headers, HeaderFailedAt, time.Now().Format(time.RFC3339))
+
+	if !IsPermanent(err) && attempt < policy.Attempts {
+		next := attempt + 1
+		out.Headers = setHeader(out.Headers, HeaderRetryAttempt, strconv.Itoa(next))
+		out.Headers = setHeader(out.Headers, HeaderRetryAt, time.Now().Add(policy.delay(next)).Format(time.RFC3339Nano))
+		perr := b.Publish(ctx, RetryTopic(topic, next), out)
+		metrics.KafkaOperations.WithLabelValues(topic, "retry", status(perr)).Inc()
+		if perr != nil {
+			return fmt.Errorf("failed to schedule retry: %v (handler error: %v)", perr, err)
+		}
+		logger.Warn("Scheduled message retry", "topic", topic, "attempt", next, "error", err)
+		return nil
+	}
+
+	perr := b.Publish(ctx, DLQTopic(topic), out)
+	metrics.KafkaOperations.WithLabelValues(topic, "dlq", status(perr)).Inc()
+	if perr != nil {
+		return fmt.Errorf("failed to dead-letter message: %v (handler error: %v)", perr, err)
+	}
+	logger.Error("Moved message to dead-letter topic", "topic", topic, "dlq", DLQTopic(topic), "attempts", attempt, "error", err)
+	return nil
+}
+
+func header(headers []Header, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func setHeader(headers []Header, key, value string) []Header {
+	for i, h := range headers {
+		if h.Key == key {
+			headers[i].Value = []byte(value)
+			return headers
+		}
+	}
+	return append(headers, Header{Key: key, Value: []byte(value)})
+}
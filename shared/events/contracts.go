@@ -0,0 +1,124 @@
+package events
+
+//go:generate go run ../../tools/eventschema schemas
+
+import "time"
+
+// Event types. Bump an event's version whenever its payload changes in a
+// way old consumers can't read, keep the old struct and give it an Upgrade
+// method, then roll out the consumers before the producers.
+const (
+	EmailRequestedType = "auth.email_requested"
+	CartItemAddedType  = "cart.item_added"
+	OTPRequestedType   = "order.otp_requested"
+)
+
+func init() {
+	Register(
+		&EmailRequestedV1{}, &EmailRequested{},
+		&CartItemAddedV1{}, &CartItemAdded{},
+		&OTPRequestedV1{}, &OTPRequested{},
+	)
+}
+
+// Email kinds sent by the email consumer.
+const (
+	EmailWelcome       = "welcome"
+	EmailVerification  = "verify_email"
+	EmailPasswordReset = "password_reset"
+	EmailAccountLocked = "account_locked"
+)
+
+// EmailRequested asks auth-service's email consumer to send an email.
+type EmailRequested struct {
+	Kind      string `json:"kind" proto:"1" schema:"required,enum=welcome|verify_email|password_reset|account_locked"`
+	Email     string `json:"email" proto:"2" schema:"required,format=email"`
+	Name      string `json:"name" proto:"3"`
+	CreatedAt string `json:"created_at,omitempty" proto:"4"`
+	Link      string `json:"link,omitempty" proto:"5"`
+	// LockedUntil is set on account_locked emails.
+	LockedUntil string `json:"locked_until,omitempty" proto:"6" schema:"format=date-time"`
+}
+
+func (*EmailRequested) EventType() string { return EmailRequestedType }
+func (*EmailRequested) EventVersion() int { return 2 }
+
+// EmailRequestedV1 is the camelCase payload email-topic originally carried.
+// Messages without a type are welcome emails.
+type EmailRequestedV1 struct {
+	Type        string `json:"type" proto:"1"`
+	Email       string `json:"email" proto:"2" schema:"required"`
+	Name        string `json:"name" proto:"3"`
+	CreatedAt   string `json:"createdAt" proto:"4"`
+	Link        string `json:"link,omitempty" proto:"5"`
+	LockedUntil string `json:"lockedUntil,omitempty" proto:"6"`
+}
+
+func (*EmailRequestedV1) EventType() string { return EmailRequestedType }
+func (*EmailRequestedV1) EventVersion() int { return 1 }
+
+func (e *EmailRequestedV1) Upgrade() Event {
+	kind := e.Type
+	if kind == "" {
+		kind = EmailWelcome
+	}
+	return &EmailRequested{
+		Kind:        kind,
+		Email:       e.Email,
+		Name:        e.Name,
+		CreatedAt:   e.CreatedAt,
+		Link:        e.Link,
+		LockedUntil: e.LockedUntil,
+	}
+}
+
+// CartItemAdded tells product-service to take items out of stock.
+type CartItemAdded struct {
+	ProductId string `json:"product_id" proto:"1" schema:"required"`
+	Quantity  int    `json:"quantity" proto:"2" schema:"required,min=1"`
+	UserId    int    `json:"user_id" proto:"3" schema:"required,min=1"`
+}
+
+func (*CartItemAdded) EventType() string { return CartItemAddedType }
+func (*CartItemAdded) EventVersion() int { return 2 }
+
+type CartItemAddedV1 struct {
+	Quantity  int    `json:"quantity" proto:"1" schema:"required"`
+	ProductId string `json:"productId" proto:"2" schema:"required"`
+	UserId    int    `json:"userId" proto:"3"`
+}
+
+func (*CartItemAddedV1) EventType() string { return CartItemAddedType }
+func (*CartItemAddedV1) EventVersion() int { return 1 }
+
+func (e *CartItemAddedV1) Upgrade() Event {
+	return &CartItemAdded{ProductId: e.ProductId, Quantity: e.Quantity, UserId: e.UserId}
+}
+
+// OTPRequested asks order-service's OTP consumer to generate and email an
+// OTP for a login session.
+type OTPRequested struct {
+	Email       string    `json:"email" proto:"1" schema:"required,format=email"`
+	UserId      int       `json:"user_id" proto:"2" schema:"required,min=1"`
+	SessionId   string    `json:"session_id" proto:"3" schema:"required"`
+	RequestedAt time.Time `json:"requested_at" proto:"4" schema:"required,format=date-time"`
+}
+
+func (*OTPRequested) EventType() string { return OTPRequestedType }
+func (*OTPRequested) EventVersion() int { return 2 }
+
+type OTPRequestedV1 struct {
+	Email     string `json:"email" proto:"1" schema:"required"`
+	UserId    int    `json:"userId" proto:"2" schema:"required"`
+	SessionId string `json:"sessionId" proto:"3" schema:"required"`
+	// CreatedAt is formatted as "2006-01-02 15:04:05" in local time.
+	CreatedAt string `json:"createdAt" proto:"4"`
+}
+
+func (*OTPRequestedV1) EventType() string { return OTPRequestedType }
+func (*OTPRequestedV1) EventVersion() int { return 1 }
+
+func (e *OTPRequestedV1) Upgrade() Event {
+	requestedAt, _ := time.ParseInLocation("2006-01-02 15:04:05", e.CreatedAt, time.Local)
+	return &OTPRequested{Email: e.Email, UserId: e.UserId, SessionId: e.SessionId, RequestedAt: requestedAt}
+}
@@ -0,0 +1,128 @@
+// Package events defines the events services exchange over the message bus
+// and the envelope every one of them is wrapped in.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope wraps an event payload. ID is unique per event and stays the same
+// when the message is retried, replayed or redelivered, so consumers use it
+// to skip events they have already handled.
+type Envelope struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Version is the version of Type's payload schema. Envelopes written
+	// before versions existed carry version 1.
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Source is the service that produced the event.
+	Source string `json:"source,omitempty"`
+	// TraceParent and TraceState are the W3C trace context the event was
+	// produced in.
+	TraceParent string          `json:"traceparent,omitempty"`
+	TraceState  string          `json:"tracestate,omitempty"`
+	Data        json.RawMessage `json:"data"`
+}
+
+var source string
+
+// SetSource names the service producing events from this process.
+func SetSource(service string) {
+	source = service
+}
+
+// New validates e against its schema and wraps it in an envelope with a
+// fresh ID.
+func New(e Event) (*Envelope, error) {
+	if err := Validate(e); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		ID:         uuid.NewString(),
+		Type:       e.EventType(),
+		Version:    e.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Source:     source,
+		Data:       payload,
+	}, nil
+}
+
+// Content types of event payloads.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+// Decode unmarshals an enveloped event into e. Payloads published before
+// envelopes existed are decoded as version 1 and return an envelope without
+// an ID.
+func Decode(value []byte, e Event) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(value, &env); err != nil || env.ID == "" || len(env.Data) == 0 {
+		env = Envelope{Type: e.EventType(), Version: 1, Data: bytes.Clone(value)}
+	}
+	return &env, DecodeData(&env, ContentTypeJSON, env.Data, e)
+}
+
+// DecodeData unmarshals data, the payload of the event described by env,
+// into e. Payloads of an older version are upgraded to e's version; newer
+// versions are rejected, since e can't represent them.
+func DecodeData(env *Envelope, contentType string, data []byte, e Event) error {
+	var unmarshal func([]byte, Event) error
+	switch mediaType(contentType) {
+	case "", ContentTypeJSON:
+		unmarshal = func(b []byte, e Event) error { return json.Unmarshal(b, e) }
+	case ContentTypeProtobuf, "application/x-protobuf":
+		unmarshal = UnmarshalProto
+	default:
+		return fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	if env.Version == 0 {
+		env.Version = 1
+	}
+	if env.Type != e.EventType() {
+		return fmt.Errorf("expected a %s event, got %s", e.EventType(), env.Type)
+	}
+	if env.Version == e.EventVersion() {
+		return unmarshal(data, e)
+	}
+	if env.Version > e.EventVersion() {
+		return fmt.Errorf("%s v%d is newer than the supported v%d", env.Type, env.Version, e.EventVersion())
+	}
+
+	old, ok := Lookup(env.Type, env.Version)
+	if !ok {
+		return fmt.Errorf("unknown event %s v%d", env.Type, env.Version)
+	}
+	if err := unmarshal(data, old); err != nil {
+		return err
+	}
+	for old.EventVersion() < e.EventVersion() {
+		u, ok := old.(Upgrader)
+		if !ok {
+			return fmt.Errorf("%s v%d can't be upgraded", env.Type, old.EventVersion())
+		}
+		old = u.Upgrade()
+	}
+	reflect.ValueOf(e).Elem().Set(reflect.ValueOf(old).Elem())
+	return nil
+}
+
+// mediaType strips parameters such as charset from a content type.
+func mediaType(contentType string) string {
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
@@ -0,0 +1,243 @@
+package events
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Events are encoded as Protobuf from the field numbers in their `proto`
+// tags, with no generated code. ProtoFile describes the same messages for
+// consumers in other languages. Once published, a field number must never be
+// reused for a different field.
+
+type protoField struct {
+	index int
+	num   protowire.Number
+	name  string
+	typ   reflect.Type
+}
+
+// protoFields returns the tagged fields of t ordered by field number, or an
+// error if a field is untagged, numbered twice or of an unsupported type.
+func protoFields(t reflect.Type) ([]protoField, error) {
+	var fields []protoField
+	seen := map[protowire.Number]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		n, err := strconv.Atoi(f.Tag.Get("proto"))
+		num := protowire.Number(n)
+		if err != nil || !num.IsValid() || seen[num] {
+			return nil, fmt.Errorf("%s.%s needs a unique proto field number", t.Name(), f.Name)
+		}
+		if protoType(f.Type) == "" {
+			return nil, fmt.Errorf("%s.%s has a type with no Protobuf encoding", t.Name(), f.Name)
+		}
+		seen[num] = true
+		fields = append(fields, protoField{index: i, num: num, name: name, typ: f.Type})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].num < fields[j].num })
+	return fields, nil
+}
+
+func protoType(t reflect.Type) string {
+	if t == timeType {
+		return "google.protobuf.Timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int64"
+	case reflect.Float64:
+		return "double"
+	}
+	return ""
+}
+
+// MarshalProto encodes e as Protobuf. Zero values are omitted, as in proto3.
+func MarshalProto(e Event) ([]byte, error) {
+	v := reflect.ValueOf(e).Elem()
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if fv.IsZero() {
+			continue
+		}
+		switch protoType(f.typ) {
+		case "string":
+			b = protowire.AppendTag(b, f.num, protowire.BytesType)
+			b = protowire.AppendString(b, fv.String())
+		case "bool":
+			b = protowire.AppendTag(b, f.num, protowire.VarintType)
+			b = protowire.AppendVarint(b, protowire.EncodeBool(fv.Bool()))
+		case "int64":
+			b = protowire.AppendTag(b, f.num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(fv.Int()))
+		case "double":
+			b = protowire.AppendTag(b, f.num, protowire.Fixed64Type)
+			b = protowire.AppendFixed64(b, math.Float64bits(fv.Float()))
+		case "google.protobuf.Timestamp":
+			t := fv.Interface().(time.Time)
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(t.Unix()))
+			if t.Nanosecond() != 0 {
+				ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+				ts = protowire.AppendVarint(ts, uint64(t.Nanosecond()))
+			}
+			b = protowire.AppendTag(b, f.num, protowire.BytesType)
+			b = protowire.AppendBytes(b, ts)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalProto decodes the Protobuf encoding of e. Unknown fields are
+// skipped, so fields can be added without breaking older consumers.
+func UnmarshalProto(b []byte, e Event) error {
+	v := reflect.ValueOf(e).Elem()
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return err
+	}
+	byNum := make(map[protowire.Number]protoField, len(fields))
+	for _, f := range fields {
+		byNum[f.num] = f
+	}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f, ok := byNum[num]
+		if !ok || !wireTypeMatches(f.typ, typ) {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		fv := v.Field(f.index)
+		switch protoType(f.typ) {
+		case "string":
+			var s string
+			s, n = protowire.ConsumeString(b)
+			fv.SetString(s)
+		case "bool":
+			var x uint64
+			x, n = protowire.ConsumeVarint(b)
+			fv.SetBool(protowire.DecodeBool(x))
+		case "int64":
+			var x uint64
+			x, n = protowire.ConsumeVarint(b)
+			fv.SetInt(int64(x))
+		case "double":
+			var x uint64
+			x, n = protowire.ConsumeFixed64(b)
+			fv.SetFloat(math.Float64frombits(x))
+		case "google.protobuf.Timestamp":
+			var ts []byte
+			ts, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				t, err := unmarshalTimestamp(ts)
+				if err != nil {
+					return err
+				}
+				fv.Set(reflect.ValueOf(t))
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func wireTypeMatches(t reflect.Type, typ protowire.Type) bool {
+	switch protoType(t) {
+	case "string", "google.protobuf.Timestamp":
+		return typ == protowire.BytesType
+	case "double":
+		return typ == protowire.Fixed64Type
+	default:
+		return typ == protowire.VarintType
+	}
+}
+
+func unmarshalTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return time.Time{}, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.VarintType || (num != 1 && num != 2) {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return time.Time{}, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return time.Time{}, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == 1 {
+			seconds = int64(x)
+		} else {
+			nanos = int64(int32(x))
+		}
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// ProtoMessageName is the name of e's message in ProtoFile.
+func ProtoMessageName(e Event) string {
+	return reflect.TypeOf(e).Elem().Name()
+}
+
+// ProtoFile returns a proto3 file declaring a message for every registered
+// event version.
+func ProtoFile() string {
+	var sb strings.Builder
+	sb.WriteString("// Code generated by tools/eventschema. DO NOT EDIT.\n\n")
+	sb.WriteString("syntax = \"proto3\";\n\npackage events;\n\nimport \"google/protobuf/timestamp.proto\";\n")
+	for _, e := range Registered() {
+		fields, err := protoFields(reflect.TypeOf(e).Elem())
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(&sb, "\n// %s v%d\nmessage %s {\n", e.EventType(), e.EventVersion(), ProtoMessageName(e))
+		for _, f := range fields {
+			fmt.Fprintf(&sb, "  %s %s = %d;\n", protoType(f.typ), f.name, f.num)
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
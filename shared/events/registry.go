@@ -0,0 +1,74 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Event is a typed event payload. Every version of an event is its own
+// struct; the latest one is what producers publish and consumers decode
+// into.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Upgrader is implemented by old versions of an event, so consumers can
+// still handle them while producers are being rolled out.
+type Upgrader interface {
+	// Upgrade converts the event to the next version.
+	Upgrade() Event
+}
+
+type key struct {
+	eventType string
+	version   int
+}
+
+var registry = map[key]reflect.Type{}
+
+// Register makes every version of the events in es known to Decode and the
+// schema generator. es must be pointers to structs whose fields all have a
+// proto tag.
+func Register(es ...Event) {
+	for _, e := range es {
+		k := key{e.EventType(), e.EventVersion()}
+		if _, ok := registry[k]; ok {
+			panic(fmt.Sprintf("events: %s v%d registered twice", k.eventType, k.version))
+		}
+		if _, err := protoFields(reflect.TypeOf(e).Elem()); err != nil {
+			panic(fmt.Sprintf("events: %v", err))
+		}
+		registry[k] = reflect.TypeOf(e).Elem()
+	}
+}
+
+// Lookup returns a new, empty event of the given type and version.
+func Lookup(eventType string, version int) (Event, bool) {
+	t, ok := registry[key{eventType, version}]
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(t).Interface().(Event), true
+}
+
+// Registered returns an empty instance of every registered event, ordered by
+// type and version.
+func Registered() []Event {
+	keys := make([]key, 0, len(registry))
+	for k := range registry {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].eventType != keys[j].eventType {
+			return keys[i].eventType < keys[j].eventType
+		}
+		return keys[i].version < keys[j].version
+	})
+	es := make([]Event, len(keys))
+	for i, k := range keys {
+		es[i], _ = Lookup(k.eventType, k.version)
+	}
+	return es
+}
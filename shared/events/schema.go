@@ -0,0 +1,202 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schemas are generated from the json tags of an event's fields and their
+// `schema` tags, a comma-separated list of:
+//
+//	required          the field must be present and, for strings, non-empty
+//	enum=a|b          the value must be one of the listed strings
+//	min=N             numbers must be at least N
+//	format=email      strings must be an email address
+//	format=date-time  strings must be an RFC 3339 timestamp
+//
+// Validate checks a payload against the same generated schema, so the
+// published schema and what producers enforce can't drift apart.
+
+// SchemaID identifies the schema of an event type and version.
+func SchemaID(eventType string, version int) string {
+	return fmt.Sprintf("urn:events:%s:v%d", eventType, version)
+}
+
+// ParseSchemaID returns the event type and version SchemaID encoded in id.
+func ParseSchemaID(id string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(id, "urn:events:")
+	if !ok {
+		return "", 0, false
+	}
+	i := strings.LastIndex(rest, ":v")
+	if i < 0 {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(rest[i+2:])
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[:i], version, true
+}
+
+// Schema returns the JSON Schema (draft 2020-12) for e's payload.
+func Schema(e Event) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	t := reflect.TypeOf(e).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := map[string]interface{}{"type": jsonType(f.Type)}
+		for _, opt := range strings.Split(f.Tag.Get("schema"), ",") {
+			opt, value, _ := strings.Cut(opt, "=")
+			switch opt {
+			case "required":
+				required = append(required, name)
+				if f.Type.Kind() == reflect.String {
+					prop["minLength"] = 1
+				}
+			case "enum":
+				prop["enum"] = strings.Split(value, "|")
+			case "min":
+				n, _ := strconv.ParseFloat(value, 64)
+				prop["minimum"] = n
+			case "format":
+				prop["format"] = value
+			}
+		}
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  SchemaID(e.EventType(), e.EventVersion()),
+		"title":                fmt.Sprintf("%s v%d", e.EventType(), e.EventVersion()),
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func jsonType(t reflect.Type) string {
+	if t == timeType {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// Validate checks e's JSON encoding against Schema(e).
+func Validate(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return err
+	}
+
+	schema := Schema(e)
+	for _, name := range schema["required"].([]string) {
+		if _, ok := doc[name]; !ok {
+			return fmt.Errorf("invalid %s event: %s is required", e.EventType(), name)
+		}
+	}
+	for name, p := range schema["properties"].(map[string]interface{}) {
+		value, ok := doc[name]
+		if !ok {
+			continue
+		}
+		if err := validateValue(p.(map[string]interface{}), value); err != nil {
+			return fmt.Errorf("invalid %s event: %s %v", e.EventType(), name, err)
+		}
+	}
+	return nil
+}
+
+func validateValue(prop map[string]interface{}, value interface{}) error {
+	switch prop["type"] {
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || (prop["type"] == "integer" && n != math.Trunc(n)) {
+			return fmt.Errorf("must be an %s", prop["type"])
+		}
+		if min, ok := prop["minimum"].(float64); ok && n < min {
+			return fmt.Errorf("must be at least %v", min)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		if minLength, ok := prop["minLength"].(int); ok && len(s) < minLength {
+			return fmt.Errorf("must not be empty")
+		}
+		if enum, ok := prop["enum"].([]string); ok && !contains(enum, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(enum, ", "))
+		}
+		switch prop["format"] {
+		case "email":
+			if !ValidEmail(s) {
+				return fmt.Errorf("must be an email address")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("must be an RFC 3339 timestamp")
+			}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+	}
+	return nil
+}
+
+// ValidEmail reports whether s is a bare email address, which is what
+// format=email accepts. Producers check addresses with it before they are
+// stored, so events about them can't fail validation later.
+func ValidEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/RohithBN/shared/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the correlation ID between services, both as an
+// HTTP header and as a Kafka message header.
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey struct{}
+
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// Init configures the JSON logger for service and routes the standard log
+// package through it, so existing log.Printf calls are structured too.
+func Init(service string) {
+	Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service", service)
+	slog.SetDefault(Logger)
+}
+
+// NewRequestID returns a random 128-bit hex ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID stores id in ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// RequestIDFrom returns the request ID stored in ctx, if any.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromContext returns the logger annotated with the request ID and trace ID
+// from ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := Logger
+	if id := RequestIDFrom(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	return logger
+}
+
+// RequestID accepts an incoming X-Request-ID or generates one, and makes it
+// available on the request headers (so proxies forward it), the response
+// and the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = NewRequestID()
+		}
+		c.Request.Header.Set(RequestIDHeader, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog writes one structured line per request. It replaces gin's
+// default text logger.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_id", c.GetHeader("X-User-ID")),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// NewRouter returns a gin engine for service with recovery, tracing, request
+// IDs and structured access logging installed. The gin context falls back to
+// the request context so it can be passed straight to database and Kafka
+// clients.
+func NewRouter(service string) *gin.Engine {
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(gin.Recovery(), tracing.Middleware(service), RequestID(), AccessLog())
+	return router
+}
+
+// KafkaHeaders returns the message headers that carry the request ID from
+// ctx to consumers.
+func KafkaHeaders(ctx context.Context) []kafka.Header {
+	id := RequestIDFrom(ctx)
+	if id == "" {
+		return nil
+	}
+	return []kafka.Header{{Key: RequestIDHeader, Value: []byte(id)}}
+}
+
+// ContextFromMessage returns ctx carrying the request ID stamped on m by the
+// producer, or a freshly generated one for messages without it.
+func ContextFromMessage(ctx context.Context, m kafka.Message) context.Context {
+	for _, h := range m.Headers {
+		if h.Key == RequestIDHeader {
+			return WithRequestID(ctx, string(h.Value))
+		}
+	}
+	return WithRequestID(ctx, NewRequestID())
+}
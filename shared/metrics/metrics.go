@@ -41,6 +41,41 @@ var (
 		},
 		[]string{"topic", "operation", "status"},
 	)
+	UpstreamInstanceUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_upstream_instance_up",
+			Help: "Whether a gateway upstream instance is eligible for traffic (1) or not (0)",
+		},
+		[]string{"service", "instance"},
+	)
+	UpstreamActiveRequests = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_upstream_active_requests",
+			Help: "Number of in-flight requests per gateway upstream instance",
+		},
+		[]string{"service", "instance"},
+	)
+	UpstreamEjections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_upstream_ejections_total",
+			Help: "Total number of passive ejections of gateway upstream instances",
+		},
+		[]string{"service", "instance"},
+	)
+	CircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_circuit_breaker_state",
+			Help: "Gateway circuit breaker state per service (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"service"},
+	)
+	UpstreamRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_upstream_retries_total",
+			Help: "Total number of retried gateway upstream requests",
+		},
+		[]string{"service"},
+	)
 )
 
 func PrometheusMiddleware() gin.HandlerFunc {
@@ -60,3 +95,11 @@ func PrometheusMiddleware() gin.HandlerFunc {
 func RegisterMetricsEndpoint(router *gin.Engine) {
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 }
+
+// RegisterHealthEndpoint exposes the liveness probe polled by the gateway
+// load balancer.
+func RegisterHealthEndpoint(router *gin.Engine) {
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+}
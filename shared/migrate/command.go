@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage: migrate [-dry-run] <command>
+
+commands:
+  status      list migrations and whether they are applied
+  up [n]      apply all pending migrations, or the next n
+  down [n]    revert the last applied migration, or the last n
+`
+
+// Command runs the migrate subcommand described by args (everything after
+// "migrate" on the command line), writing output to out.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprint(out, usage) }
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run without executing it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	m.DryRun = *dryRun
+	m.Out = out
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+	n := 0
+	if flags.NArg() > 1 {
+		var err error
+		if n, err = strconv.Atoi(flags.Arg(1)); err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q", flags.Arg(1))
+		}
+	}
+
+	switch flags.Arg(0) {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			at := "pending"
+			if s.Applied {
+				at = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		return w.Flush()
+	case "up":
+		done, err := m.Up(ctx, n)
+		report(out, "Applied", done, m.DryRun)
+		return err
+	case "down":
+		done, err := m.Down(ctx, n)
+		report(out, "Reverted", done, m.DryRun)
+		return err
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+}
+
+func report(out io.Writer, verb string, done []Migration, dryRun bool) {
+	if dryRun {
+		verb = "Would have " + strings.ToLower(verb)
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(out, "Nothing to do")
+		return
+	}
+	for _, mig := range done {
+		fmt.Fprintf(out, "%s %d_%s\n", verb, mig.Version, mig.Name)
+	}
+}
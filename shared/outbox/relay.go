@@ -0,0 +1,109 @@
+// Package outbox publishes the events services store in their outbox
+// together with the database change they describe.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/logging"
+	"github.com/RohithBN/shared/metrics"
+	"github.com/RohithBN/shared/repository"
+)
+
+const maxBackoff = 5 * time.Minute
+
+// Relay moves records from an Outbox to the bus. Any number of relays can
+// share an outbox: each batch is leased to one of them, and a record whose
+// relay dies is picked up by another once the lease runs out. That can
+// publish a record twice, which consumers already tolerate.
+type Relay struct {
+	Outbox    repository.Outbox
+	Publisher bus.Publisher
+	// Interval is how long to wait after an empty batch.
+	Interval time.Duration
+	Batch    int
+	Lease    time.Duration
+
+	owner string
+}
+
+func NewRelay(ob repository.Outbox, pub bus.Publisher) *Relay {
+	host, _ := os.Hostname()
+	return &Relay{
+		Outbox:    ob,
+		Publisher: pub,
+		Interval:  time.Second,
+		Batch:     100,
+		Lease:     30 * time.Second,
+		owner:     fmt.Sprintf("%s-%s", host, logging.NewRequestID()[:8]),
+	}
+}
+
+// Run publishes pending records until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.Flush(ctx)
+		if err != nil {
+			logging.Logger.Error("Outbox relay failed", "error", err)
+		}
+		if n == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.Interval):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush publishes one batch of due records and returns how many it claimed.
+// Records that fail to publish are released with an exponential backoff.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	records, err := r.Outbox.Claim(ctx, r.owner, r.Batch, r.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox records: %v", err)
+	}
+
+	// Claim hands out at most one record per key, so a failed record
+	// holds back the later ones with its key until it is sent.
+	for _, rec := range records {
+		err := r.Publisher.Publish(ctx, rec.Topic, rec.Message)
+		metrics.KafkaOperations.WithLabelValues(rec.Topic, "produce", status(err)).Inc()
+		if err == nil {
+			if err := r.Outbox.MarkSent(ctx, rec.ID); err != nil {
+				return len(records), fmt.Errorf("failed to mark outbox record %s sent: %v", rec.ID, err)
+			}
+			continue
+		}
+
+		logging.Logger.Warn("Failed to publish outbox record", "id", rec.ID, "topic", rec.Topic, "attempts", rec.Attempts+1, "error", err)
+		if err := r.Outbox.MarkFailed(ctx, rec.ID, err, time.Now().Add(backoff(rec.Attempts))); err != nil {
+			return len(records), fmt.Errorf("failed to release outbox record %s: %v", rec.ID, err)
+		}
+	}
+	return len(records), nil
+}
+
+// backoff doubles from one second with every failed attempt.
+func backoff(attempts int) time.Duration {
+	if attempts >= 9 {
+		return maxBackoff
+	}
+	if d := time.Second << attempts; d < maxBackoff {
+		return d
+	}
+	return maxBackoff
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+// ProcessedEventTTL is how long handled event IDs are remembered, which is
+// far longer than a message spends being retried.
+const ProcessedEventTTL = 24 * time.Hour
+
+func processedEventKey(consumer, eventId string) string {
+	return "processed_event:" + consumer + ":" + eventId
+}
+
+// EventProcessed reports whether consumer has already handled eventId. For
+// side effects outside a database, like sending an email, where checking
+// before and marking after is as close to exactly-once as we can get.
+func EventProcessed(ctx context.Context, consumer, eventId string) (bool, error) {
+	n, err := RedisClient.Exists(ctx, processedEventKey(consumer, eventId)).Result()
+	return n > 0, err
+}
+
+func MarkEventProcessed(ctx context.Context, consumer, eventId string) error {
+	return RedisClient.Set(ctx, processedEventKey(consumer, eventId), "1", ProcessedEventTTL).Err()
+}
@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Emailed order OTPs are stored hashed, per user and login session:
+//
+//	otp:<user_id>:<session_id>   hash {hash, attempts}
+//	otp_cooldown:<user_id>       set while a new OTP can't be requested
+//
+// A code is deleted once it is used or its attempts run out.
+func otpKey(userId int, sessionId string) string { return fmt.Sprintf("otp:%d:%s", userId, sessionId) }
+func otpCooldownKey(userId int) string           { return fmt.Sprintf("otp_cooldown:%d", userId) }
+
+var (
+	ErrOTPNotFound        = errors.New("OTP not found or expired")
+	ErrOTPInvalid         = errors.New("invalid OTP")
+	ErrOTPTooManyAttempts = errors.New("too many invalid attempts")
+)
+
+// OTP settings are read lazily because .env is loaded in main.
+func OTPTTL() time.Duration            { return envDuration("OTP_TTL", 10*time.Minute) }
+func OTPResendCooldown() time.Duration { return envDuration("OTP_RESEND_COOLDOWN", time.Minute) }
+func OTPMaxAttempts() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("OTP_MAX_ATTEMPTS"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return 5
+}
+
+func hashOTP(key, code string) string {
+	sum := sha256.Sum256([]byte(key + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// StoreOTP replaces the session's OTP with code.
+func StoreOTP(ctx context.Context, userId int, sessionId, code string) error {
+	key := otpKey(userId, sessionId)
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "hash", hashOTP(key, code), "attempts", 0)
+	pipe.Expire(ctx, key, OTPTTL())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// CheckOTP verifies code against the session's OTP, consuming one attempt.
+func CheckOTP(ctx context.Context, userId int, sessionId, code string) error {
+	key := otpKey(userId, sessionId)
+
+	// HINCRBY is atomic, so concurrent guesses can't exceed the limit
+	attempts, err := RedisClient.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return err
+	}
+	stored, err := RedisClient.HGet(ctx, key, "hash").Result()
+	if err == redis.Nil {
+		// HINCRBY created an empty hash for a missing OTP
+		RedisClient.Del(ctx, key)
+		return ErrOTPNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	max := OTPMaxAttempts()
+	if attempts > max {
+		RedisClient.Del(ctx, key)
+		return ErrOTPTooManyAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(hashOTP(key, code))) != 1 {
+		if attempts == max {
+			RedisClient.Del(ctx, key)
+		}
+		return ErrOTPInvalid
+	}
+	return RedisClient.Del(ctx, key).Err()
+}
+
+// StartOTPCooldown reports whether userId may request a new OTP and starts
+// the cooldown if so. Otherwise it returns the time left.
+func StartOTPCooldown(ctx context.Context, userId int) (bool, time.Duration, error) {
+	ok, err := RedisClient.SetNX(ctx, otpCooldownKey(userId), 1, OTPResendCooldown()).Result()
+	if err != nil || ok {
+		return ok, 0, err
+	}
+	ttl, err := RedisClient.PTTL(ctx, otpCooldownKey(userId)).Result()
+	return false, ttl, err
+}
+
+// ClearOTPCooldown ends userId's cooldown early, for when the OTP it was
+// started for couldn't be sent.
+func ClearOTPCooldown(ctx context.Context, userId int) error {
+	return RedisClient.Del(ctx, otpCooldownKey(userId)).Err()
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes from a bucket stored as a hash in a
+// single atomic step. Redis server time is used so that gateway replicas
+// with skewed clocks share the same view of the bucket.
+//
+// KEYS[1] bucket key
+// ARGV[1] capacity, ARGV[2] refill rate (tokens/second), ARGV[3] cost
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or capacity
+local ts = tonumber(data[2]) or now
+
+local elapsed = math.max(0, now - ts) / 1000
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// TakeToken atomically refills the bucket at key and tries to take cost
+// tokens from it. It returns whether the request is allowed and the tokens
+// left in the bucket afterwards.
+func TakeToken(ctx context.Context, key string, capacity, refillPerSecond, cost float64) (bool, float64, error) {
+	res, err := tokenBucketScript.Run(ctx, RedisClient, []string{key},
+		capacity, refillPerSecond, cost).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	allowed, _ := res[0].(int64)
+	remaining, err := strconv.ParseFloat(res[1].(string), 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, remaining, nil
+}
+
+// RetryAfter returns how long a caller has to wait for one token.
+func RetryAfter(remaining, refillPerSecond float64) time.Duration {
+	if remaining >= 1 || refillPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration((1 - remaining) / refillPerSecond * float64(time.Second))
+}
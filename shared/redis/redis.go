@@ -3,7 +3,9 @@ package redis
 import (
 	"context"
 	"fmt"
+	"os"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -12,12 +14,20 @@ var ctx = context.Background()
 var RedisClient *redis.Client
 
 func ConnectRedis() error {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // Replace with your Redis server address
+		Addr:     addr,
 		Password: "",               // No password for local development
 		DB:       0,                // Default DB
 	})
 
+	if err := redisotel.InstrumentTracing(RedisClient); err != nil {
+		return fmt.Errorf("failed to instrument redis: %v", err)
+	}
+
 	pong, err := RedisClient.Ping(ctx).Result()
 	if err != nil {
 		return err		
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func revokedKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+// RevokeToken adds the access token identified by jti to the revocation
+// list. ttl should cover the token's remaining lifetime; once it expires the
+// token is rejected on its own and the entry is no longer needed.
+func RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if jti == "" {
+		return nil
+	}
+	return RedisClient.Set(ctx, revokedKey(jti), "1", ttl).Err()
+}
+
+// IsTokenRevoked reports whether the access token identified by jti has
+// been revoked.
+func IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	err := RedisClient.Get(ctx, revokedKey(jti)).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Second-factor methods that can step a user up before placing orders.
+const (
+	StepUpEmailOTP = "email_otp"
+	StepUpTOTP     = "totp"
+)
+
+// StepUpTTL is how long a successful second-factor check lasts.
+const StepUpTTL = 30 * time.Minute
+
+// Step-ups are bound to the login session (X-Session-ID) that performed
+// them, so a token from another session of the same user doesn't inherit
+// them.
+func stepUpKey(userId int, sessionId, method string) string {
+	return fmt.Sprintf("stepup:%d:%s:%s", userId, sessionId, method)
+}
+
+// MarkSteppedUp records that the session passed a second-factor check with
+// method.
+func MarkSteppedUp(ctx context.Context, userId int, sessionId, method string) error {
+	return RedisClient.Set(ctx, stepUpKey(userId, sessionId, method), "true", StepUpTTL).Err()
+}
+
+// SteppedUp returns the method the session recently stepped up with, or ""
+// if there is none.
+func SteppedUp(ctx context.Context, userId int, sessionId string) (string, error) {
+	if sessionId == "" {
+		return "", nil
+	}
+	methods := []string{StepUpTOTP, StepUpEmailOTP}
+	keys := make([]string, len(methods))
+	for i, m := range methods {
+		keys[i] = stepUpKey(userId, sessionId, m)
+	}
+	values, err := RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return "", err
+	}
+	for i, v := range values {
+		if s, ok := v.(string); ok && s == "true" {
+			return methods[i], nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,624 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// In-memory implementations for handler tests. They return copies, so
+// callers can't change stored records without going through the repository.
+
+type MemoryUsers struct {
+	mu       sync.Mutex
+	nextId   int
+	users    map[int]types.User
+	Attempts []types.LoginAttempt
+}
+
+func NewMemoryUserRepository() *MemoryUsers {
+	return &MemoryUsers{users: map[int]types.User{}}
+}
+
+func (r *MemoryUsers) emailTaken(email string, except int) bool {
+	for id, u := range r.users {
+		if id != except && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryUsers) Create(ctx context.Context, user *types.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.emailTaken(user.Email, 0) {
+		return ErrDuplicate
+	}
+	r.nextId++
+	user.Id = r.nextId
+	r.users[user.Id] = *user
+	return nil
+}
+
+func (r *MemoryUsers) GetByID(ctx context.Context, id int) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUsers) GetByEmail(ctx context.Context, email string) (*types.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	email = strings.TrimSpace(email)
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUsers) update(id int, fn func(*types.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(&user)
+	r.users[id] = user
+	return nil
+}
+
+func (r *MemoryUsers) UpdateProfile(ctx context.Context, user *types.User) error {
+	r.mu.Lock()
+	taken := r.emailTaken(user.Email, user.Id)
+	r.mu.Unlock()
+	if taken {
+		return ErrDuplicate
+	}
+	return r.update(user.Id, func(u *types.User) {
+		u.Name, u.Email, u.EmailVerified = user.Name, user.Email, user.EmailVerified
+	})
+}
+
+func (r *MemoryUsers) SetPassword(ctx context.Context, id int, hash string) error {
+	return r.update(id, func(u *types.User) { u.Password = hash })
+}
+
+func (r *MemoryUsers) SetEmailVerified(ctx context.Context, id int, verified bool) error {
+	return r.update(id, func(u *types.User) { u.EmailVerified = verified })
+}
+
+func (r *MemoryUsers) RecordLoginAttempt(ctx context.Context, attempt types.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Attempts = append(r.Attempts, attempt)
+	return nil
+}
+
+type memoryTOTP struct {
+	secret  string
+	enabled bool
+	codes   map[string]bool // code hash -> used
+}
+
+type MemoryMFA struct {
+	mu    sync.Mutex
+	totp  map[int]*memoryTOTP
+	Audit []string // "<user id>:<event>"
+}
+
+func NewMemoryMFARepository() *MemoryMFA {
+	return &MemoryMFA{totp: map[int]*memoryTOTP{}}
+}
+
+func (r *MemoryMFA) GetTOTP(ctx context.Context, userId int) (string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.totp[userId]
+	if !ok {
+		return "", false, ErrNotFound
+	}
+	return t.secret, t.enabled, nil
+}
+
+func (r *MemoryMFA) StartTOTP(ctx context.Context, userId int, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.totp[userId]; ok {
+		t.secret = secret
+		return nil
+	}
+	r.totp[userId] = &memoryTOTP{secret: secret}
+	return nil
+}
+
+func (r *MemoryMFA) EnableTOTP(ctx context.Context, userId int, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.totp[userId]
+	if !ok {
+		return ErrNotFound
+	}
+	t.enabled = true
+	t.codes = map[string]bool{}
+	for _, hash := range codeHashes {
+		t.codes[hash] = false
+	}
+	return nil
+}
+
+func (r *MemoryMFA) DisableTOTP(ctx context.Context, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.totp, userId)
+	return nil
+}
+
+func (r *MemoryMFA) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.totp[userId]
+	if !ok {
+		return false, nil
+	}
+	used, ok := t.codes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	t.codes[codeHash] = true
+	return true, nil
+}
+
+func (r *MemoryMFA) RecordAudit(ctx context.Context, userId int, event, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Audit = append(r.Audit, strconv.Itoa(userId)+":"+event)
+	return nil
+}
+
+type MemoryAddresses struct {
+	mu        sync.Mutex
+	nextId    int
+	addresses []types.Address
+}
+
+func NewMemoryAddressRepository() *MemoryAddresses {
+	return &MemoryAddresses{}
+}
+
+// find returns the index of address id owned by userId, or -1.
+func (r *MemoryAddresses) find(userId, id int) int {
+	for i, a := range r.addresses {
+		if a.Id == id && a.UserId == userId {
+			return i
+		}
+	}
+	return -1
+}
+
+// ListByUser sorts like the Postgres query: by kind, default first, then by id.
+func (r *MemoryAddresses) ListByUser(ctx context.Context, userId int) ([]types.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addresses := []types.Address{}
+	for _, a := range r.addresses {
+		if a.UserId == userId {
+			addresses = append(addresses, a)
+		}
+	}
+	sort.SliceStable(addresses, func(i, j int) bool {
+		a, b := addresses[i], addresses[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.IsDefault && !b.IsDefault
+	})
+	return addresses, nil
+}
+
+func (r *MemoryAddresses) Get(ctx context.Context, userId, id int) (*types.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(userId, id)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	a := r.addresses[i]
+	return &a, nil
+}
+
+func (r *MemoryAddresses) Create(ctx context.Context, address *types.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	makeDefault := address.IsDefault
+	if !makeDefault {
+		makeDefault = true
+		for _, a := range r.addresses {
+			if a.UserId == address.UserId && a.Kind == address.Kind {
+				makeDefault = false
+			}
+		}
+	}
+	r.nextId++
+	address.Id = r.nextId
+	address.IsDefault = false
+	address.CreatedAt = time.Now().Format(time.RFC3339)
+	r.addresses = append(r.addresses, *address)
+	if makeDefault {
+		r.setDefault(len(r.addresses) - 1)
+		address.IsDefault = true
+	}
+	return nil
+}
+
+func (r *MemoryAddresses) Update(ctx context.Context, address *types.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(address.UserId, address.Id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	existing := r.addresses[i]
+	address.Kind, address.IsDefault, address.CreatedAt = existing.Kind, existing.IsDefault, existing.CreatedAt
+	r.addresses[i] = *address
+	return nil
+}
+
+func (r *MemoryAddresses) Delete(ctx context.Context, userId, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(userId, id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.addresses = append(r.addresses[:i], r.addresses[i+1:]...)
+	return nil
+}
+
+func (r *MemoryAddresses) SetDefault(ctx context.Context, userId, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(userId, id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.setDefault(i)
+	return nil
+}
+
+// setDefault makes the address at i the only default of its user and kind.
+func (r *MemoryAddresses) setDefault(i int) {
+	target := r.addresses[i]
+	for j, a := range r.addresses {
+		if a.UserId == target.UserId && a.Kind == target.Kind {
+			r.addresses[j].IsDefault = j == i
+		}
+	}
+}
+
+type MemoryProducts struct {
+	mu       sync.Mutex
+	products map[primitive.ObjectID]types.Product
+}
+
+func NewMemoryProductRepository() *MemoryProducts {
+	return &MemoryProducts{products: map[primitive.ObjectID]types.Product{}}
+}
+
+func (r *MemoryProducts) List(ctx context.Context) ([]types.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var products []types.Product
+	for _, product := range r.products {
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func (r *MemoryProducts) Get(ctx context.Context, id primitive.ObjectID) (*types.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	product, ok := r.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &product, nil
+}
+
+func (r *MemoryProducts) Create(ctx context.Context, product *types.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	r.products[product.ID] = *product
+	return nil
+}
+
+func (r *MemoryProducts) Update(ctx context.Context, product *types.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.products[product.ID]
+	if !ok {
+		// Matches Mongo, where updating a missing product is not an error
+		return nil
+	}
+	product.CreatedAt = existing.CreatedAt
+	r.products[product.ID] = *product
+	return nil
+}
+
+func (r *MemoryProducts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.products[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.products, id)
+	return nil
+}
+
+func (r *MemoryProducts) AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if product, ok := r.products[id]; ok {
+		product.Stock += delta
+		r.products[id] = product
+	}
+	return nil
+}
+
+type MemoryCarts struct {
+	mu    sync.Mutex
+	carts map[int]types.Cart
+}
+
+func NewMemoryCartRepository() *MemoryCarts {
+	return &MemoryCarts{carts: map[int]types.Cart{}}
+}
+
+func (r *MemoryCarts) Get(ctx context.Context, userId int) (*types.Cart, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cart, ok := r.carts[userId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cart.Products = append([]types.Product(nil), cart.Products...)
+	return &cart, nil
+}
+
+func (r *MemoryCarts) Save(ctx context.Context, cart *types.Cart) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *cart
+	saved.Products = append([]types.Product(nil), cart.Products...)
+	r.carts[cart.UserId] = saved
+	return nil
+}
+
+func (r *MemoryCarts) Delete(ctx context.Context, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.carts, userId)
+	return nil
+}
+
+type MemoryOrders struct {
+	mu     sync.Mutex
+	orders []types.Order
+	Audit  []types.AuditEntry
+}
+
+func NewMemoryOrderRepository() *MemoryOrders {
+	return &MemoryOrders{}
+}
+
+// find returns the index of order id, or -1. Unless anyOwner is set it
+// must belong to ownerId.
+func (r *MemoryOrders) find(id primitive.ObjectID, ownerId int, anyOwner bool) int {
+	for i, order := range r.orders {
+		if order.OrderId == id && (anyOwner || order.UserId == ownerId) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *MemoryOrders) Create(ctx context.Context, order *types.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order.OrderId = primitive.NewObjectID()
+	r.orders = append(r.orders, *order)
+	return nil
+}
+
+func (r *MemoryOrders) ListByUser(ctx context.Context, userId int) ([]types.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var orders []types.Order
+	for _, order := range r.orders {
+		if order.UserId == userId {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *MemoryOrders) Exists(ctx context.Context, id primitive.ObjectID, ownerId int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(id, ownerId, false) >= 0, nil
+}
+
+func (r *MemoryOrders) ExistsUnscoped(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(id, 0, true) >= 0, nil
+}
+
+func (r *MemoryOrders) UpdateStatus(ctx context.Context, id primitive.ObjectID, ownerId int, status string) error {
+	return r.updateStatus(id, ownerId, false, status)
+}
+
+func (r *MemoryOrders) UpdateStatusUnscoped(ctx context.Context, id primitive.ObjectID, status string) error {
+	return r.updateStatus(id, 0, true, status)
+}
+
+func (r *MemoryOrders) updateStatus(id primitive.ObjectID, ownerId int, anyOwner bool, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(id, ownerId, anyOwner)
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.orders[i].Status = status
+	return nil
+}
+
+func (r *MemoryOrders) RecordAudit(ctx context.Context, entry types.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Audit = append(r.Audit, entry)
+	return nil
+}
+
+type MemoryProcessedEvents struct {
+	mu        sync.Mutex
+	processed map[string]bool
+}
+
+func NewMemoryProcessedEvents() *MemoryProcessedEvents {
+	return &MemoryProcessedEvents{processed: map[string]bool{}}
+}
+
+func (r *MemoryProcessedEvents) Once(ctx context.Context, consumer, eventId string, fn func(ctx context.Context) error) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := consumer + ":" + eventId
+	if r.processed[key] {
+		return false, nil
+	}
+	if err := fn(ctx); err != nil {
+		return false, err
+	}
+	r.processed[key] = true
+	return true, nil
+}
+
+type memoryOutboxRecord struct {
+	OutboxRecord
+	nextAttempt time.Time
+	lockedUntil time.Time
+}
+
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	nextId  int
+	records []*memoryOutboxRecord
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (o *MemoryOutbox) Add(ctx context.Context, topic string, msg bus.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.nextId++
+	o.records = append(o.records, &memoryOutboxRecord{
+		OutboxRecord: OutboxRecord{ID: strconv.Itoa(o.nextId), Topic: topic, Message: msg},
+	})
+	return nil
+}
+
+func (o *MemoryOutbox) Claim(ctx context.Context, owner string, limit int, lease time.Duration) ([]OutboxRecord, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	var claimed []OutboxRecord
+	// Keys that have an earlier unsent record
+	pending := make(map[string]bool)
+	for _, rec := range o.records {
+		if len(claimed) == limit {
+			break
+		}
+		key := rec.Topic + "/" + string(rec.Message.Key)
+		waiting := rec.Message.Key != nil && pending[key]
+		pending[key] = true
+		if !waiting && !rec.nextAttempt.After(now) && !rec.lockedUntil.After(now) {
+			rec.lockedUntil = now.Add(lease)
+			claimed = append(claimed, rec.OutboxRecord)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *MemoryOutbox) find(id string) int {
+	for i, rec := range o.records {
+		if rec.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *MemoryOutbox) MarkSent(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := o.find(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	o.records = append(o.records[:i], o.records[i+1:]...)
+	return nil
+}
+
+func (o *MemoryOutbox) MarkFailed(ctx context.Context, id string, cause error, next time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := o.find(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	rec := o.records[i]
+	rec.Attempts++
+	rec.nextAttempt, rec.lockedUntil = next, time.Time{}
+	return nil
+}
+
+// Pending returns the records not yet published.
+func (o *MemoryOutbox) Pending() []OutboxRecord {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var pending []OutboxRecord
+	for _, rec := range o.records {
+		pending = append(pending, rec.OutboxRecord)
+	}
+	return pending
+}
+
+var (
+	_ Outbox            = (*MemoryOutbox)(nil)
+	_ ProcessedEvents   = (*MemoryProcessedEvents)(nil)
+	_ UserRepository    = (*MemoryUsers)(nil)
+	_ MFARepository     = (*MemoryMFA)(nil)
+	_ AddressRepository = (*MemoryAddresses)(nil)
+	_ ProductRepository = (*MemoryProducts)(nil)
+	_ CartRepository    = (*MemoryCarts)(nil)
+	_ OrderRepository   = (*MemoryOrders)(nil)
+)
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/RohithBN/shared/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCarts struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCartRepository stores carts in the "carts" collection, keyed by
+// userid.
+func NewMongoCartRepository(db *mongo.Database) CartRepository {
+	return &mongoCarts{collection: db.Collection("carts")}
+}
+
+func (r *mongoCarts) Get(ctx context.Context, userId int) (*types.Cart, error) {
+	var cart types.Cart
+	err := r.collection.FindOne(ctx, bson.M{"userid": userId}).Decode(&cart)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (r *mongoCarts) Save(ctx context.Context, cart *types.Cart) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"userid": cart.UserId},
+		bson.M{"$set": bson.M{
+			"products":   cart.Products,
+			"totalprice": cart.TotalPrice,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoCarts) Delete(ctx context.Context, userId int) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"userid": userId})
+	return err
+}
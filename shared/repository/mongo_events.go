@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoProcessedEvents struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// NewMongoProcessedEvents records handled events in the "processed_events"
+// collection. It uses multi-document transactions, so MongoDB must run as a
+// replica set (Atlas always does). Records expire through the index
+// EnsureProcessedEventsTTL creates.
+func NewMongoProcessedEvents(db *mongo.Database) ProcessedEvents {
+	return &mongoProcessedEvents{client: db.Client(), collection: db.Collection("processed_events")}
+}
+
+const processedEventsTTLIndex = "processed_at_ttl"
+
+// processedEventsTTL is how long a handled event's id is remembered
+// (PROCESSED_EVENTS_TTL, default 30 days). It must outlast the topic's
+// retention, or a replayed message older than its record is applied again;
+// Kafka keeps messages for 7 days by default.
+func processedEventsTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("PROCESSED_EVENTS_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * 24 * time.Hour
+}
+
+// EnsureProcessedEventsTTL adds the TTL index that lets MongoDB expire
+// "processed_events" records, or updates its expiry if it already exists.
+func EnsureProcessedEventsTTL(ctx context.Context, db *mongo.Database) error {
+	seconds := int32(processedEventsTTL().Seconds())
+	_, err := db.Collection("processed_events").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "processed_at", Value: 1}},
+		Options: options.Index().SetName(processedEventsTTLIndex).SetExpireAfterSeconds(seconds),
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexOptionsConflict" {
+		return db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: "processed_events"},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: processedEventsTTLIndex},
+				{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	}
+	return err
+}
+
+var errAlreadyProcessed = errors.New("event already processed")
+
+func (r *mongoProcessedEvents) Once(ctx context.Context, consumer, eventId string, fn func(ctx context.Context) error) (bool, error) {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return false, err
+	}
+	defer session.EndSession(ctx)
+
+	// The _id makes a second insert for the same event fail, which aborts
+	// the transaction along with whatever fn wrote.
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := r.collection.InsertOne(sc, bson.M{
+			"_id":          consumer + ":" + eventId,
+			"consumer":     consumer,
+			"event_id":     eventId,
+			"processed_at": time.Now(),
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errAlreadyProcessed
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, fn(sc)
+	})
+	if errors.Is(err, errAlreadyProcessed) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/RohithBN/shared/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoOrders struct {
+	orders *mongo.Collection
+	audit  *mongo.Collection
+}
+
+// NewMongoOrderRepository stores orders in the "orders" collection and audit
+// entries in "audit_log".
+func NewMongoOrderRepository(db *mongo.Database) OrderRepository {
+	return &mongoOrders{orders: db.Collection("orders"), audit: db.Collection("audit_log")}
+}
+
+func ownedBy(id primitive.ObjectID, ownerId int) bson.M {
+	return bson.M{"_id": id, "userid": ownerId}
+}
+
+func (r *mongoOrders) Create(ctx context.Context, order *types.Order) error {
+	result, err := r.orders.InsertOne(ctx, order)
+	if err != nil {
+		return err
+	}
+	order.OrderId = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *mongoOrders) ListByUser(ctx context.Context, userId int) ([]types.Order, error) {
+	cursor, err := r.orders.Find(ctx, bson.M{"userid": userId})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []types.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *mongoOrders) Exists(ctx context.Context, id primitive.ObjectID, ownerId int) (bool, error) {
+	return r.exists(ctx, ownedBy(id, ownerId))
+}
+
+func (r *mongoOrders) ExistsUnscoped(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.exists(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoOrders) exists(ctx context.Context, filter bson.M) (bool, error) {
+	count, err := r.orders.CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+func (r *mongoOrders) UpdateStatus(ctx context.Context, id primitive.ObjectID, ownerId int, status string) error {
+	return r.updateStatus(ctx, ownedBy(id, ownerId), status)
+}
+
+func (r *mongoOrders) UpdateStatusUnscoped(ctx context.Context, id primitive.ObjectID, status string) error {
+	return r.updateStatus(ctx, bson.M{"_id": id}, status)
+}
+
+func (r *mongoOrders) updateStatus(ctx context.Context, filter bson.M, status string) error {
+	result, err := r.orders.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"status": status}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoOrders) RecordAudit(ctx context.Context, entry types.AuditEntry) error {
+	_, err := r.audit.InsertOne(ctx, entry)
+	return err
+}
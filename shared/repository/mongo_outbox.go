@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohithBN/shared/bus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoOutbox struct {
+	collection *mongo.Collection
+}
+
+// NewMongoOutbox stores events in the "outbox" collection.
+func NewMongoOutbox(db *mongo.Database) Outbox {
+	return &mongoOutbox{collection: db.Collection("outbox")}
+}
+
+type outboxHeader struct {
+	Key   string `bson:"key"`
+	Value []byte `bson:"value"`
+}
+
+type outboxDoc struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Topic         string             `bson:"topic"`
+	Key           []byte             `bson:"key"`
+	Value         []byte             `bson:"value"`
+	Headers       []outboxHeader     `bson:"headers"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	Attempts      int                `bson:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	LastError     string             `bson:"last_error,omitempty"`
+	LockedBy      string             `bson:"locked_by,omitempty"`
+	LockedUntil   *time.Time         `bson:"locked_until"`
+	SentAt        *time.Time         `bson:"sent_at"`
+}
+
+func (o *mongoOutbox) Add(ctx context.Context, topic string, msg bus.Message) error {
+	now := time.Now()
+	doc := outboxDoc{
+		Topic:         topic,
+		Key:           msg.Key,
+		Value:         msg.Value,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	for _, h := range msg.Headers {
+		doc.Headers = append(doc.Headers, outboxHeader{Key: h.Key, Value: h.Value})
+	}
+	_, err := o.collection.InsertOne(ctx, doc)
+	return err
+}
+
+func (o *mongoOutbox) Claim(ctx context.Context, owner string, limit int, lease time.Duration) ([]OutboxRecord, error) {
+	now := time.Now()
+	due := bson.M{
+		"sent_at":         nil,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": nil},
+			bson.M{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	cursor, err := o.collection.Find(ctx, due, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []OutboxRecord
+	// A record waits while an earlier one with the same key is unsent, so
+	// only the oldest record of each key is ever claimed and per-key order
+	// holds across batches and relays.
+	seen := make(map[string]bool)
+	for len(records) < limit && cursor.Next(ctx) {
+		var doc outboxDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return records, err
+		}
+		if doc.Key != nil {
+			key := doc.Topic + "/" + string(doc.Key)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			earlier, err := o.collection.CountDocuments(ctx,
+				bson.M{"topic": doc.Topic, "key": doc.Key, "sent_at": nil, "_id": bson.M{"$lt": doc.ID}},
+				options.Count().SetLimit(1),
+			)
+			if err != nil {
+				return records, err
+			}
+			if earlier > 0 {
+				continue
+			}
+		}
+
+		// The claim itself is a single atomic findAndModify, so relays on
+		// several replicas never get the same record.
+		filter := bson.M{"_id": doc.ID}
+		for k, v := range due {
+			filter[k] = v
+		}
+		err := o.collection.FindOneAndUpdate(ctx,
+			filter,
+			bson.M{"$set": bson.M{"locked_by": owner, "locked_until": now.Add(lease)}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Another relay claimed it first
+			continue
+		}
+		if err != nil {
+			return records, err
+		}
+
+		rec := OutboxRecord{
+			ID:       doc.ID.Hex(),
+			Topic:    doc.Topic,
+			Message:  bus.Message{Key: doc.Key, Value: doc.Value},
+			Attempts: doc.Attempts,
+		}
+		for _, h := range doc.Headers {
+			rec.Message.Headers = append(rec.Message.Headers, bus.Header{Key: h.Key, Value: h.Value})
+		}
+		records = append(records, rec)
+	}
+	return records, cursor.Err()
+}
+
+func (o *mongoOutbox) MarkSent(ctx context.Context, id string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = o.collection.DeleteOne(ctx, bson.M{"_id": objId})
+	return err
+}
+
+func (o *mongoOutbox) MarkFailed(ctx context.Context, id string, cause error, next time.Time) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = o.collection.UpdateOne(ctx, bson.M{"_id": objId}, bson.M{
+		"$set":   bson.M{"last_error": cause.Error(), "next_attempt_at": next, "locked_until": nil},
+		"$unset": bson.M{"locked_by": ""},
+		"$inc":   bson.M{"attempts": 1},
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/RohithBN/shared/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoProducts struct {
+	collection *mongo.Collection
+}
+
+// NewMongoProductRepository stores products in the "products" collection.
+func NewMongoProductRepository(db *mongo.Database) ProductRepository {
+	return &mongoProducts{collection: db.Collection("products")}
+}
+
+func (r *mongoProducts) List(ctx context.Context) ([]types.Product, error) {
+	cursor, err := r.collection.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []types.Product
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *mongoProducts) Get(ctx context.Context, id primitive.ObjectID) (*types.Product, error) {
+	var product types.Product
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *mongoProducts) Create(ctx context.Context, product *types.Product) error {
+	result, err := r.collection.InsertOne(ctx, product)
+	if err != nil {
+		return err
+	}
+	product.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *mongoProducts) Update(ctx context.Context, product *types.Product) error {
+	update := bson.M{
+		"name":          product.Name,
+		"price":         product.Price,
+		"description":   product.Description,
+		"updated_at":    product.UpdatedAt,
+		"added_to_cart": product.AddedToCart,
+		"category":      product.Category,
+		"stock":         product.Stock,
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": product.ID}, bson.M{"$set": update})
+	return err
+}
+
+func (r *mongoProducts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProducts) AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"stock": delta}})
+	return err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohithBN/shared/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type postgresAddresses struct {
+	pool *pgxpool.Pool
+	tx   Transactor
+}
+
+// NewPostgresAddressRepository stores addresses in the addresses table. A
+// partial unique index keeps at most one default per user and kind.
+func NewPostgresAddressRepository(pool *pgxpool.Pool) AddressRepository {
+	return &postgresAddresses{pool: pool, tx: NewPostgresTransactor(pool)}
+}
+
+const addressColumns = `id, user_id, kind, label, recipient, line1, line2, city, state, postal_code, country, phone, is_default, created_at`
+
+func scanAddress(row pgx.Row, a *types.Address) error {
+	var createdAt time.Time
+	err := row.Scan(&a.Id, &a.UserId, &a.Kind, &a.Label, &a.Recipient, &a.Line1, &a.Line2,
+		&a.City, &a.State, &a.PostalCode, &a.Country, &a.Phone, &a.IsDefault, &createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	a.CreatedAt = createdAt.Format(time.RFC3339)
+	return err
+}
+
+func (r *postgresAddresses) ListByUser(ctx context.Context, userId int) ([]types.Address, error) {
+	rows, err := pgConn(ctx, r.pool).Query(
+		ctx,
+		`SELECT `+addressColumns+` FROM addresses WHERE user_id = $1 ORDER BY kind, is_default DESC, id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []types.Address{}
+	for rows.Next() {
+		var a types.Address
+		if err := scanAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (r *postgresAddresses) Get(ctx context.Context, userId, id int) (*types.Address, error) {
+	var a types.Address
+	row := pgConn(ctx, r.pool).QueryRow(ctx, `SELECT `+addressColumns+` FROM addresses WHERE id = $1 AND user_id = $2`, id, userId)
+	if err := scanAddress(row, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *postgresAddresses) Create(ctx context.Context, a *types.Address) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		conn := pgConn(ctx, r.pool)
+		var existing int
+		if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM addresses WHERE user_id = $1 AND kind = $2`, a.UserId, a.Kind).Scan(&existing); err != nil {
+			return err
+		}
+		makeDefault := a.IsDefault || existing == 0
+
+		row := conn.QueryRow(
+			ctx,
+			`INSERT INTO addresses (user_id, kind, label, recipient, line1, line2, city, state, postal_code, country, phone, is_default, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, false, $12) RETURNING `+addressColumns,
+			a.UserId, a.Kind, a.Label, a.Recipient, a.Line1, a.Line2, a.City, a.State, a.PostalCode, a.Country, a.Phone, time.Now(),
+		)
+		if err := scanAddress(row, a); err != nil {
+			return err
+		}
+		if !makeDefault {
+			return nil
+		}
+		if err := r.SetDefault(ctx, a.UserId, a.Id); err != nil {
+			return err
+		}
+		a.IsDefault = true
+		return nil
+	})
+}
+
+func (r *postgresAddresses) Update(ctx context.Context, a *types.Address) error {
+	row := pgConn(ctx, r.pool).QueryRow(
+		ctx,
+		`UPDATE addresses SET label = $1, recipient = $2, line1 = $3, line2 = $4, city = $5, state = $6, postal_code = $7, country = $8, phone = $9
+		 WHERE id = $10 AND user_id = $11 RETURNING `+addressColumns,
+		a.Label, a.Recipient, a.Line1, a.Line2, a.City, a.State, a.PostalCode, a.Country, a.Phone, a.Id, a.UserId,
+	)
+	return scanAddress(row, a)
+}
+
+func (r *postgresAddresses) Delete(ctx context.Context, userId, id int) error {
+	tag, err := pgConn(ctx, r.pool).Exec(ctx, `DELETE FROM addresses WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresAddresses) SetDefault(ctx context.Context, userId, id int) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		conn := pgConn(ctx, r.pool)
+		var kind string
+		err := conn.QueryRow(ctx, `SELECT kind FROM addresses WHERE id = $1 AND user_id = $2`, id, userId).Scan(&kind)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Exec(ctx, `UPDATE addresses SET is_default = false WHERE user_id = $1 AND kind = $2 AND is_default`, userId, kind); err != nil {
+			return err
+		}
+		_, err = conn.Exec(ctx, `UPDATE addresses SET is_default = true WHERE id = $1`, id)
+		return err
+	})
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type postgresMFA struct {
+	pool *pgxpool.Pool
+	tx   Transactor
+}
+
+// NewPostgresMFARepository stores enrolments in user_mfa, recovery codes in
+// mfa_recovery_codes and the audit log in mfa_audit.
+func NewPostgresMFARepository(pool *pgxpool.Pool) MFARepository {
+	return &postgresMFA{pool: pool, tx: NewPostgresTransactor(pool)}
+}
+
+func (r *postgresMFA) GetTOTP(ctx context.Context, userId int) (string, bool, error) {
+	var secret string
+	var enabled bool
+	err := pgConn(ctx, r.pool).QueryRow(ctx, `SELECT totp_secret, enabled FROM user_mfa WHERE user_id = $1`, userId).
+		Scan(&secret, &enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, ErrNotFound
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return secret, enabled, nil
+}
+
+func (r *postgresMFA) StartTOTP(ctx context.Context, userId int, secret string) error {
+	_, err := pgConn(ctx, r.pool).Exec(
+		ctx,
+		`INSERT INTO user_mfa (user_id, totp_secret, enabled, created_at) VALUES ($1, $2, false, $3)
+		 ON CONFLICT (user_id) DO UPDATE SET totp_secret = EXCLUDED.totp_secret, created_at = EXCLUDED.created_at`,
+		userId,
+		secret,
+		time.Now(),
+	)
+	return err
+}
+
+func (r *postgresMFA) EnableTOTP(ctx context.Context, userId int, codeHashes []string) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		conn := pgConn(ctx, r.pool)
+		tag, err := conn.Exec(ctx, `UPDATE user_mfa SET enabled = true, enabled_at = $1 WHERE user_id = $2`, time.Now(), userId)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		if _, err := conn.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+			return err
+		}
+		for _, hash := range codeHashes {
+			if _, err := conn.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userId, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *postgresMFA) DisableTOTP(ctx context.Context, userId int) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		conn := pgConn(ctx, r.pool)
+		if _, err := conn.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+			return err
+		}
+		_, err := conn.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userId)
+		return err
+	})
+}
+
+func (r *postgresMFA) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	tag, err := pgConn(ctx, r.pool).Exec(
+		ctx,
+		`UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		time.Now(),
+		userId,
+		codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *postgresMFA) RecordAudit(ctx context.Context, userId int, event, ip string) error {
+	_, err := pgConn(ctx, r.pool).Exec(
+		ctx,
+		`INSERT INTO mfa_audit (user_id, event, ip, created_at) VALUES ($1, $2, $3, $4)`,
+		userId,
+		event,
+		ip,
+		time.Now(),
+	)
+	return err
+}
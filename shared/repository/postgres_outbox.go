@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type postgresOutbox struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresOutbox stores events in the outbox table.
+func NewPostgresOutbox(pool *pgxpool.Pool) Outbox {
+	return &postgresOutbox{pool: pool}
+}
+
+func (o *postgresOutbox) Add(ctx context.Context, topic string, msg bus.Message) error {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = pgConn(ctx, o.pool).Exec(
+		ctx,
+		`INSERT INTO outbox (topic, key, value, headers) VALUES ($1, $2, $3, $4)`,
+		topic,
+		msg.Key,
+		msg.Value,
+		headers,
+	)
+	return err
+}
+
+func (o *postgresOutbox) Claim(ctx context.Context, owner string, limit int, lease time.Duration) ([]OutboxRecord, error) {
+	// SKIP LOCKED lets relays on several replicas claim disjoint batches. A
+	// record waits while an earlier one with the same key is unsent, so only
+	// the oldest record of each key is ever claimed and per-key order holds
+	// across batches and relays.
+	rows, err := o.pool.Query(
+		ctx,
+		`WITH claimed AS (
+			UPDATE outbox SET locked_by = $1, locked_until = now() + $2::float8 * interval '1 second'
+			WHERE id IN (
+				SELECT id FROM outbox
+				WHERE sent_at IS NULL AND next_attempt_at <= now()
+					AND (locked_until IS NULL OR locked_until < now())
+					AND NOT EXISTS (
+						SELECT 1 FROM outbox o2
+						WHERE o2.topic = outbox.topic AND o2.key = outbox.key
+							AND o2.sent_at IS NULL AND o2.id < outbox.id
+					)
+				ORDER BY id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, topic, key, value, headers, attempts
+		)
+		SELECT * FROM claimed ORDER BY id`,
+		owner,
+		lease.Seconds(),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []OutboxRecord
+	for rows.Next() {
+		var id int64
+		var headers []byte
+		var rec OutboxRecord
+		if err := rows.Scan(&id, &rec.Topic, &rec.Message.Key, &rec.Message.Value, &headers, &rec.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(headers, &rec.Message.Headers); err != nil {
+			return nil, err
+		}
+		rec.ID = strconv.FormatInt(id, 10)
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func (o *postgresOutbox) MarkSent(ctx context.Context, id string) error {
+	rowId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = o.pool.Exec(ctx, `DELETE FROM outbox WHERE id = $1`, rowId)
+	return err
+}
+
+func (o *postgresOutbox) MarkFailed(ctx context.Context, id string, cause error, next time.Time) error {
+	rowId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = o.pool.Exec(
+		ctx,
+		`UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, locked_by = NULL, locked_until = NULL WHERE id = $3`,
+		cause.Error(),
+		next,
+		rowId,
+	)
+	return err
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/RohithBN/shared/types"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type postgresUsers struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresUserRepository stores users in the USERS table and login
+// attempts in login_attempts.
+func NewPostgresUserRepository(pool *pgxpool.Pool) UserRepository {
+	return &postgresUsers{pool: pool}
+}
+
+const userColumns = `id, name, email, password, roles, created_at, email_verified`
+
+func (r *postgresUsers) get(ctx context.Context, where string, arg interface{}) (*types.User, error) {
+	var user types.User
+	err := pgConn(ctx, r.pool).QueryRow(ctx, `SELECT `+userColumns+` FROM USERS WHERE `+where, arg).
+		Scan(&user.Id, &user.Name, &user.Email, &user.Password, &user.Roles, &user.CreatedAt, &user.EmailVerified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// exec runs an UPDATE and maps "no rows" and unique violations to
+// ErrNotFound and ErrDuplicate.
+func (r *postgresUsers) exec(ctx context.Context, sql string, args ...interface{}) error {
+	tag, err := pgConn(ctx, r.pool).Exec(ctx, sql, args...)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresUsers) Create(ctx context.Context, user *types.User) error {
+	err := pgConn(ctx, r.pool).QueryRow(
+		ctx,
+		`INSERT INTO USERS (name, email, password, roles, created_at, email_verified) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		user.Name,
+		user.Email,
+		user.Password,
+		user.Roles,
+		user.CreatedAt,
+		user.EmailVerified,
+	).Scan(&user.Id)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *postgresUsers) GetByID(ctx context.Context, id int) (*types.User, error) {
+	return r.get(ctx, `id = $1`, id)
+}
+
+func (r *postgresUsers) GetByEmail(ctx context.Context, email string) (*types.User, error) {
+	return r.get(ctx, `LOWER(email) = $1`, strings.ToLower(strings.TrimSpace(email)))
+}
+
+func (r *postgresUsers) UpdateProfile(ctx context.Context, user *types.User) error {
+	return r.exec(ctx, `UPDATE USERS SET name = $1, email = $2, email_verified = $3 WHERE id = $4`,
+		user.Name, user.Email, user.EmailVerified, user.Id)
+}
+
+func (r *postgresUsers) SetPassword(ctx context.Context, id int, hash string) error {
+	return r.exec(ctx, `UPDATE USERS SET password = $1 WHERE id = $2`, hash, id)
+}
+
+func (r *postgresUsers) SetEmailVerified(ctx context.Context, id int, verified bool) error {
+	return r.exec(ctx, `UPDATE USERS SET email_verified = $1 WHERE id = $2`, verified, id)
+}
+
+func (r *postgresUsers) RecordLoginAttempt(ctx context.Context, attempt types.LoginAttempt) error {
+	var userId *int
+	if attempt.UserId != 0 {
+		userId = &attempt.UserId
+	}
+	_, err := pgConn(ctx, r.pool).Exec(
+		ctx,
+		`INSERT INTO login_attempts (email, user_id, ip, success, reason, attempted_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		attempt.Email,
+		userId,
+		attempt.IP,
+		attempt.Success,
+		attempt.Reason,
+		attempt.AttemptedAt,
+	)
+	return err
+}
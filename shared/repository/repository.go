@@ -0,0 +1,139 @@
+// Package repository hides the databases behind small interfaces so
+// handlers can be built with either the real Mongo/Postgres implementations
+// or the in-memory ones.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/RohithBN/shared/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+)
+
+// UserRepository stores accounts in the auth database. Users returned by it
+// carry the bcrypt hash in Password.
+type UserRepository interface {
+	// Create inserts user and sets its Id. It returns ErrDuplicate if the
+	// email is taken.
+	Create(ctx context.Context, user *types.User) error
+	GetByID(ctx context.Context, id int) (*types.User, error)
+	// GetByEmail matches the email case-insensitively.
+	GetByEmail(ctx context.Context, email string) (*types.User, error)
+	// UpdateProfile saves Name, Email and EmailVerified.
+	UpdateProfile(ctx context.Context, user *types.User) error
+	SetPassword(ctx context.Context, id int, hash string) error
+	SetEmailVerified(ctx context.Context, id int, verified bool) error
+	RecordLoginAttempt(ctx context.Context, attempt types.LoginAttempt) error
+}
+
+// MFARepository stores TOTP enrolments, recovery codes and the MFA audit
+// log. The secret is stored as given; callers encrypt it.
+type MFARepository interface {
+	// GetTOTP returns the user's secret and whether enrolment has been
+	// confirmed, or ErrNotFound if the user hasn't started one.
+	GetTOTP(ctx context.Context, userId int) (secret string, enabled bool, err error)
+	// StartTOTP stores an unconfirmed secret, replacing any earlier one.
+	StartTOTP(ctx context.Context, userId int, secret string) error
+	// EnableTOTP confirms the enrolment and replaces the recovery codes with
+	// codeHashes.
+	EnableTOTP(ctx context.Context, userId int, codeHashes []string) error
+	// DisableTOTP removes the enrolment and its recovery codes.
+	DisableTOTP(ctx context.Context, userId int) error
+	// UseRecoveryCode marks the unused code with codeHash as used and
+	// reports whether there was one.
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	RecordAudit(ctx context.Context, userId int, event, ip string) error
+}
+
+// AddressRepository stores the users' address books. Every method is scoped
+// to the address's owner and returns ErrNotFound for other users' addresses.
+type AddressRepository interface {
+	ListByUser(ctx context.Context, userId int) ([]types.Address, error)
+	Get(ctx context.Context, userId, id int) (*types.Address, error)
+	// Create inserts address and sets its Id and CreatedAt. It becomes the
+	// default for its kind if IsDefault is set or it is the first of its
+	// kind.
+	Create(ctx context.Context, address *types.Address) error
+	// Update saves every field except Kind and IsDefault and reloads the
+	// rest into address.
+	Update(ctx context.Context, address *types.Address) error
+	Delete(ctx context.Context, userId, id int) error
+	// SetDefault makes id the default for its kind, clearing the previous one.
+	SetDefault(ctx context.Context, userId, id int) error
+}
+
+type ProductRepository interface {
+	List(ctx context.Context) ([]types.Product, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*types.Product, error)
+	// Create inserts product and sets its ID.
+	Create(ctx context.Context, product *types.Product) error
+	Update(ctx context.Context, product *types.Product) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// AdjustStock adds delta (which may be negative) to the stock.
+	AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) error
+}
+
+// CartRepository stores one cart per user.
+type CartRepository interface {
+	Get(ctx context.Context, userId int) (*types.Cart, error)
+	// Save creates or replaces the user's cart.
+	Save(ctx context.Context, cart *types.Cart) error
+	Delete(ctx context.Context, userId int) error
+}
+
+// OrderRepository scopes lookups to the order's owner. The Unscoped
+// methods match orders of any user and are only meant for fulfillment roles
+// and auditing.
+type OrderRepository interface {
+	// Create inserts order and sets its OrderId.
+	Create(ctx context.Context, order *types.Order) error
+	ListByUser(ctx context.Context, userId int) ([]types.Order, error)
+	Exists(ctx context.Context, id primitive.ObjectID, ownerId int) (bool, error)
+	ExistsUnscoped(ctx context.Context, id primitive.ObjectID) (bool, error)
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, ownerId int, status string) error
+	UpdateStatusUnscoped(ctx context.Context, id primitive.ObjectID, status string) error
+	// RecordAudit appends an entry to the audit log.
+	RecordAudit(ctx context.Context, entry types.AuditEntry) error
+}
+
+// ProcessedEvents remembers which events a consumer has handled, so a
+// redelivered event doesn't apply its changes twice.
+type ProcessedEvents interface {
+	// Once runs fn unless consumer has already processed eventId, and
+	// reports whether it ran. Writes fn makes through the ctx it is given
+	// are committed together with the record of the event.
+	Once(ctx context.Context, consumer, eventId string, fn func(ctx context.Context) error) (bool, error)
+}
+
+// OutboxRecord is an event waiting in the outbox.
+type OutboxRecord struct {
+	ID       string
+	Topic    string
+	Message  bus.Message
+	Attempts int
+}
+
+// Outbox holds events until the relay has published them, so an event is
+// never lost when the write it belongs to commits but the broker is down.
+type Outbox interface {
+	// Add stores msg for topic. Call it inside Transactor.InTx so it
+	// commits or rolls back with the change it describes.
+	Add(ctx context.Context, topic string, msg bus.Message) error
+	// Claim leases up to limit due records to owner. Leased records are
+	// skipped by other relays until the lease runs out. A record isn't
+	// claimed while an earlier record with the same topic and key is unsent.
+	Claim(ctx context.Context, owner string, limit int, lease time.Duration) ([]OutboxRecord, error)
+	// MarkSent deletes the record. Events can carry secrets, such as the
+	// token in a password reset link, so they aren't kept once published.
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed releases the record to be tried again at next.
+	MarkFailed(ctx context.Context, id string, cause error, next time.Time) error
+}
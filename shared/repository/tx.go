@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs a unit of work atomically. Repository calls made with the
+// ctx passed to fn join the transaction, as do Outbox.Add calls, which is
+// how an event is stored together with the change it describes.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type pgTxKey struct{}
+
+// querier is what the Postgres repositories need from a pool or transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// pgConn returns the transaction started by InTx, if ctx is inside one, or
+// the pool.
+func pgConn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(pgTxKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+type postgresTransactor struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresTransactor(pool *pgxpool.Pool) Transactor {
+	return &postgresTransactor{pool: pool}
+}
+
+func (t *postgresTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pgTxKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := t.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, pgTxKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+// NewMongoTransactor uses multi-document transactions, so MongoDB must run
+// as a replica set.
+func NewMongoTransactor(db *mongo.Database) Transactor {
+	return &mongoTransactor{client: db.Client()}
+}
+
+func (t *mongoTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// MemoryTransactor runs fn without any isolation or rollback, which is
+// enough for the in-memory repositories.
+type MemoryTransactor struct{}
+
+func (MemoryTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
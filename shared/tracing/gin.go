@@ -0,0 +1,12 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Middleware starts a server span for every request handled by a gin router,
+// continuing any trace context sent by the caller.
+func Middleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service)
+}
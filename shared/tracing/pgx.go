@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxLogger turns pgx v4's query log events into client spans. pgx v4 has no
+// tracer hook, but it logs every Exec/Query with its duration once it
+// completes, which is enough to reconstruct the span after the fact.
+type PgxLogger struct{}
+
+func (PgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	switch msg {
+	case "Exec", "Query", "CopyFrom", "SendBatch":
+	default:
+		return
+	}
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+
+	end := time.Now()
+	start := end
+	if d, ok := data["time"].(time.Duration); ok {
+		start = end.Add(-d)
+	}
+
+	sql, _ := data["sql"].(string)
+	_, span := Tracer().Start(ctx, spanName(msg, sql),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(sql),
+		),
+	)
+	if rows, ok := data["rowCount"].(int); ok {
+		span.SetAttributes(attribute.Int("db.rows", rows))
+	}
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+func spanName(msg, sql string) string {
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		return fmt.Sprintf("postgres %s", strings.ToUpper(fields[0]))
+	}
+	return "postgres " + msg
+}
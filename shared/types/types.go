@@ -1,13 +1,62 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	Id        int    `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	CreatedAt string `json:"created_at"`
+	Id        int      `json:"id"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	Password  string   `json:"password"`
+	Roles     []string `json:"roles"`
+	CreatedAt string   `json:"created_at"`
+	// EmailVerified is set once the user follows the link sent on registration.
+	EmailVerified bool `json:"email_verified"`
+}
+
+// LoginAttempt is one row of the login_attempts audit table.
+type LoginAttempt struct {
+	Email       string
+	UserId      int // 0 when the email is unknown
+	IP          string
+	Success     bool
+	Reason      string
+	AttemptedAt time.Time
+}
+
+// Roles understood by the gateway's authorization table. New accounts are
+// always customers; staff and admin are granted directly in the database.
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// Address kinds in a user's address book. Each user has at most one default
+// address per kind.
+const (
+	AddressShipping = "shipping"
+	AddressBilling  = "billing"
+)
+
+type Address struct {
+	Id         int    `json:"id"`
+	UserId     int    `json:"user_id"`
+	Kind       string `json:"kind" binding:"required,oneof=shipping billing"`
+	Label      string `json:"label"`
+	Recipient  string `json:"recipient"`
+	Line1      string `json:"line1" binding:"required"`
+	Line2      string `json:"line2"`
+	City       string `json:"city" binding:"required"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code" binding:"required"`
+	Country    string `json:"country" binding:"required"`
+	Phone      string `json:"phone"`
+	IsDefault  bool   `json:"is_default"`
+	CreatedAt  string `json:"created_at"`
 }
 
 type Product struct {
@@ -35,4 +84,19 @@ type Order struct {
 	TotalPrice float64   `json:"total_price"`
 	CreatedAt  string    `json:"created_at"`
 	Status     string    `json:"status"`
+	// Copies of the address book entries at order time, so later edits
+	// don't change past orders.
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+	BillingAddress  *Address `json:"billing_address,omitempty"`
+}
+
+// AuditEntry is a document in the audit_log collection.
+type AuditEntry struct {
+	Event     string             `json:"event" bson:"event"`
+	Action    string             `json:"action" bson:"action"`
+	OrderId   primitive.ObjectID `json:"order_id" bson:"order_id"`
+	UserId    int                `json:"user_id" bson:"user_id"`
+	Roles     []string           `json:"roles" bson:"roles"`
+	RequestId string             `json:"request_id" bson:"request_id"`
+	CreatedAt string             `json:"created_at" bson:"created_at"`
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/RohithBN/shared/tracing"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -14,7 +16,15 @@ func ConnectDB() (*pgxpool.Pool, error) {
 
 
 	connStr := os.Getenv(("NEONDB_URI"))
-	pool, err := pgxpool.Connect(context.Background(), connStr)
+	config, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %v", err)
+	}
+	// Query events are turned into trace spans
+	config.ConnConfig.Logger = tracing.PgxLogger{}
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
 	if err != nil {
 		panic(err)
 	}
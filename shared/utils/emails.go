@@ -1,15 +1,14 @@
 package utils
 
 import (
-	"context"
+	"crypto/rand"
 	"fmt"
-	"math/rand"
+	"html"
+	"math/big"
 	"net/smtp"
 	"os"
 	"strings"
-	"time"
 
-	"github.com/RohithBN/shared/redis"
 	"github.com/RohithBN/shared/types"
 )
 
@@ -97,6 +96,117 @@ func SendEmailAfterRegistration(email string, name string, createdAt string) err
 	return SendEmail([]string{email}, subject, body)
 }
 
+func SendVerificationEmail(email string, name string, link string) error {
+	subject := "Verify your email - E-Commerce Store"
+
+	body := fmt.Sprintf(`
+        <html>
+        <head>
+            <style>
+                body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+                .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+                .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; }
+                .footer { text-align: center; margin-top: 20px; color: #666; }
+                .button { background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Confirm your email address</h1>
+                </div>
+                
+                <p>Dear %s,</p>
+                <p>Please confirm that this is your email address to finish setting up your account.</p>
+                
+                <p><a class="button" href="%s">Verify email</a></p>
+                
+                <p>This link expires in 24 hours. If you did not create an account, please ignore this email.</p>
+                
+                <div class="footer">
+                    <small>This is an automated email, please do not reply.</small>
+                </div>
+            </div>
+        </body>
+        </html>
+    `, html.EscapeString(name), html.EscapeString(link))
+
+	return SendEmail([]string{email}, subject, body)
+}
+
+func SendPasswordResetEmail(email string, name string, link string) error {
+	subject := "Reset your password - E-Commerce Store"
+
+	body := fmt.Sprintf(`
+        <html>
+        <head>
+            <style>
+                body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+                .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+                .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; }
+                .footer { text-align: center; margin-top: 20px; color: #666; }
+                .button { background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Password reset</h1>
+                </div>
+                
+                <p>Dear %s,</p>
+                <p>We received a request to reset the password for your account.</p>
+                
+                <p><a class="button" href="%s">Reset password</a></p>
+                
+                <p>This link expires in 30 minutes and can only be used once. If you did not request a password reset, you can ignore this email; your password will not change.</p>
+                
+                <div class="footer">
+                    <small>This is an automated email, please do not reply.</small>
+                </div>
+            </div>
+        </body>
+        </html>
+    `, html.EscapeString(name), html.EscapeString(link))
+
+	return SendEmail([]string{email}, subject, body)
+}
+
+func SendAccountLockedEmail(email string, name string, lockedUntil string) error {
+	subject := "Your account has been temporarily locked - E-Commerce Store"
+
+	body := fmt.Sprintf(`
+        <html>
+        <head>
+            <style>
+                body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+                .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+                .header { background-color: #E53935; color: white; padding: 20px; text-align: center; }
+                .footer { text-align: center; margin-top: 20px; color: #666; }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Account temporarily locked</h1>
+                </div>
+                
+                <p>Dear %s,</p>
+                <p>We locked your account after several failed login attempts. You can try again after <strong>%s</strong>.</p>
+                
+                <p>If these attempts were not made by you, we recommend resetting your password using the "Forgot password" option.</p>
+                
+                <div class="footer">
+                    <small>This is an automated email, please do not reply.</small>
+                </div>
+            </div>
+        </body>
+        </html>
+    `, html.EscapeString(name), html.EscapeString(lockedUntil))
+
+	return SendEmail([]string{email}, subject, body)
+}
+
 func SendOrderConfirmationEmail(toEmail string, order *types.Order) error {
 	subject := "Order Confirmation - E-Commerce Store"
 
@@ -146,15 +256,9 @@ func SendOrderConfirmationEmail(toEmail string, order *types.Order) error {
 	return SendEmail([]string{toEmail}, subject, body)
 }
 
-func SendOTPMail(email string, createdAt string) error {
-	uniqueCode := generateUniqueCode()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	err := redis.RedisClient.Set(ctx, email, uniqueCode,  10*time.Minute).Err()
-	if err != nil {
-		return fmt.Errorf("failed to set OTP in Redis: %v", err)
-	}
-    fmt.Println("OTP set in Redis:", uniqueCode)
+// SendOTPMail emails code, an OTP generated with GenerateOTP. Storing it is
+// up to the caller.
+func SendOTPMail(email string, code string) error {
 	subject := "Your OTP Code - E-Commerce Store"
 	body := fmt.Sprintf(`
 
@@ -175,7 +279,7 @@ func SendOTPMail(email string, createdAt string) error {
                 </div>
                 <p>Dear User,</p>
                 <p>Your OTP code is: <strong>%s</strong></p>
-                <p>This code is valid for a short period of time. Please use it to complete your registration.</p>
+                <p>This code is valid for a short period of time. Please use it to confirm your order.</p>
                 <p>If you did not request this code, please ignore this email.</p>
                 <div class="footer">
 
@@ -185,12 +289,15 @@ func SendOTPMail(email string, createdAt string) error {
             </div>
         </body>
         </html>
-    `, uniqueCode)
-    fmt.Println("Sending OTP email to:", email)
+    `, html.EscapeString(code))
 	return SendEmail([]string{email}, subject, body)
 }
 
-func generateUniqueCode() string {
-	// Generate a unique code (e.g., a random 6-digit number)
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+// GenerateOTP returns a random 6-digit code from crypto/rand.
+func GenerateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var MongoClient *mongo.Client
@@ -18,7 +19,7 @@ func ConnectMongoDB() error {
 	defer cancel()
 
 	mongoUri := os.Getenv(("MONGOURI"))
-	clientOptions := options.Client().ApplyURI(mongoUri)
+	clientOptions := options.Client().ApplyURI(mongoUri).SetMonitor(otelmongo.NewMonitor())
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return fmt.Errorf("MongoDB connect error: %v", err)
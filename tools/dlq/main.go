@@ -0,0 +1,26 @@
+// Command dlq lists and replays messages in the dead-letter topics.
+//
+//	go run ./tools/dlq list cart-add-item-topic
+//	go run ./tools/dlq replay cart-add-item-topic 0:12
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/RohithBN/shared/bus"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// KAFKA_BROKERS may come from the shared .env, but it's optional here
+	godotenv.Load(".env")
+
+	b := bus.NewKafka(bus.BrokersFromEnv()...)
+	defer b.Close()
+
+	if err := bus.DLQCommand(context.Background(), b, os.Args[1:], os.Stdout); err != nil {
+		log.Fatalf("dlq: %v", err)
+	}
+}
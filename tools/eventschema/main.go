@@ -0,0 +1,41 @@
+// Command eventschema writes the JSON Schema of every registered event
+// version to a directory, one <type>.v<version>.json file each, and the
+// Protobuf messages for all of them to events.proto.
+//
+//	go run ./tools/eventschema shared/events/schemas
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/RohithBN/shared/events"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: eventschema <dir>")
+	}
+	dir := os.Args[1]
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("eventschema: %v", err)
+	}
+
+	for _, e := range events.Registered() {
+		schema, err := json.MarshalIndent(events.Schema(e), "", "  ")
+		if err != nil {
+			log.Fatalf("eventschema: %v", err)
+		}
+		name := fmt.Sprintf("%s.v%d.json", e.EventType(), e.EventVersion())
+		if err := os.WriteFile(filepath.Join(dir, name), append(schema, '\n'), 0o644); err != nil {
+			log.Fatalf("eventschema: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "events.proto"), []byte(events.ProtoFile()), 0o644); err != nil {
+		log.Fatalf("eventschema: %v", err)
+	}
+}